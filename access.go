@@ -0,0 +1,38 @@
+package rbtree
+
+// writing is the flag of the access state which is set while the tree is being modified,
+// the remaining bits count active readers.
+const writing int32 = 1 << 30
+
+// beginWrite marks the tree as being modified.
+// Panics if the tree is already being read or modified.
+func (rb *rbTree) beginWrite() {
+	if rb.access.CompareAndSwap(0, writing) {
+		return
+	}
+
+	if rb.access.Load()&writing != 0 {
+		panic("rbtree: concurrent tree writes")
+	}
+
+	panic("rbtree: concurrent tree read and tree write")
+}
+
+// endWrite clears the mark set by beginWrite.
+func (rb *rbTree) endWrite() {
+	rb.access.Add(-writing)
+}
+
+// beginRead registers an active reader.
+// Panics if the tree is being modified.
+func (rb *rbTree) beginRead() {
+	if rb.access.Add(1)&writing != 0 {
+		rb.access.Add(-1)
+		panic("rbtree: concurrent tree read and tree write")
+	}
+}
+
+// endRead unregisters the reader registered by beginRead.
+func (rb *rbTree) endRead() {
+	rb.access.Add(-1)
+}
@@ -0,0 +1,96 @@
+package rbtree
+
+import "errors"
+
+// batchTxn implements Txn interface by journaling operations applied to the target.
+// Rollback reverts the operations on the undo target.
+type batchTxn struct {
+	target Txn
+	undo   Txn
+	ops    []Operation
+}
+
+// batch calls fn with a transaction over the given target and rolls back
+// its operations if fn returns an error or panics.
+func batch(target Txn, fn func(tx Txn) error) error {
+	return runBatch(target, target, fn, nil)
+}
+
+// runBatch calls fn with a transaction over the given target and then the optional commit.
+// If either of them fails or fn panics, the operations are reverted on the undo target.
+// Errors of the rollback are joined to the returned error.
+// Watchers get the change events once the batch is committed, those of a rolled back batch
+// are discarded unless the rollback fails.
+func runBatch(target, undo Txn, fn func(tx Txn) error, commit func() error) error {
+	tx := &batchTxn{target: target, undo: undo}
+	release := holdEvents(undo)
+
+	defer func() {
+		if r := recover(); r != nil {
+			release(tx.rollback() != nil)
+			panic(r)
+		}
+	}()
+
+	err := fn(tx)
+	if err == nil && commit != nil {
+		err = commit()
+	}
+
+	if err != nil {
+		rollbackErr := tx.rollback()
+		if rollbackErr != nil {
+			err = errors.Join(err, rollbackErr)
+		}
+
+		release(rollbackErr != nil)
+		return err
+	}
+
+	release(true)
+	return nil
+}
+
+// Insert adds the given item to the tree, see Tree.Insert.
+func (tx *batchTxn) Insert(item Item) (bool, error) {
+	old := tx.target.Find(item)
+
+	ok, err := tx.target.Insert(item)
+	if err != nil {
+		return ok, err
+	}
+
+	tx.ops = append(tx.ops, insertion(item, old))
+	return ok, nil
+}
+
+// Remove deletes an item equals to the given item from the tree, see Tree.Remove.
+func (tx *batchTxn) Remove(item Item) (bool, error) {
+	old := tx.target.Find(item)
+
+	ok, err := tx.target.Remove(item)
+	if ok {
+		tx.ops = append(tx.ops, Operation{Kind: OpRemove, Item: old})
+	}
+
+	return ok, err
+}
+
+// Returns the item if the given key is in the tree, otherwise return nil.
+func (tx *batchTxn) Find(item Item) Item {
+	return tx.target.Find(item)
+}
+
+// rollback reverts the journaled operations in reverse order.
+// Returns the errors of the operations that could not be reverted.
+func (tx *batchTxn) rollback() error {
+	var errs []error
+	for i := len(tx.ops) - 1; i >= 0; i-- {
+		if err := revert(tx.undo, tx.ops[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	tx.ops = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package rbtree
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// ErrorItemOutOfOrder informs that an item was added to the Builder before a smaller one.
+var ErrorItemOutOfOrder error = errors.New("Given item is less than the previously added one")
+
+// Builder constructs a balanced tree from items supplied in ascending order.
+// The zero value is ready to use.
+type Builder struct {
+	items []Item
+}
+
+// NewBuilder returns a new instance of Builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Add appends the given item to the tree being built.
+// An item equal to the previously added one replaces it.
+// Returns an error if the item is less than the previously added one.
+func (b *Builder) Add(item Item) error {
+	n := len(b.items)
+	if n == 0 {
+		b.items = append(b.items, item)
+		return nil
+	}
+
+	last := b.items[n-1]
+	if item.Less(last) {
+		return ErrorItemOutOfOrder
+	}
+
+	if !last.Less(item) {
+		b.items[n-1] = item
+		return nil
+	}
+
+	b.items = append(b.items, item)
+	return nil
+}
+
+// Build returns a balanced tree that contains all added items in O(n).
+// The builder is reset and can be reused afterwards.
+func (b *Builder) Build() Tree {
+	rb := New().(*rbTree)
+	rb.load(b.items)
+	b.items = nil
+
+	return rb
+}
+
+// load replaces the content of the tree with the given items, which must be
+// sorted in ascending order without duplicates.
+func (rb *rbTree) load(items []Item) {
+	rb.rebuilds++
+	rb.root = buildBalanced(items)
+	rb.length = len(items)
+	rb.median = tNil
+
+	if rb.length > 0 {
+		rb.median, _ = rb.find(items[(rb.length+1)/2-1])
+	}
+
+	if rb.filter != nil {
+		rb.filter.reset()
+		for _, item := range items {
+			rb.filterAdd(item)
+		}
+	}
+}
+
+// buildBalanced returns the root of a valid red-black tree that holds the given sorted items.
+// Every level is full except possibly the deepest one, whose nodes are colored red.
+func buildBalanced(items []Item) *node {
+	n := len(items)
+	redDepth := -1
+	if n&(n+1) != 0 { // the last level is incomplete
+		redDepth = bits.Len(uint(n)) - 1
+	}
+
+	return buildSubtree(items, tNil, 0, redDepth)
+}
+
+// buildSubtree links the given sorted items under the parent node.
+func buildSubtree(items []Item, parent *node, depth, redDepth int) *node {
+	if len(items) == 0 {
+		return tNil
+	}
+
+	mid := len(items) / 2
+	x := &node{black, items[mid], tNil, tNil, parent}
+	if depth == redDepth {
+		x.color = red
+	}
+
+	x.left = buildSubtree(items[:mid], x, depth+1, redDepth)
+	x.right = buildSubtree(items[mid+1:], x, depth+1, redDepth)
+
+	return x
+}
@@ -0,0 +1,299 @@
+// Command rbtree-viz renders the structure of a red-black tree step by step.
+//
+// It reads an operation script from the given file or stdin, one command per line:
+//
+//	insert <key>       adds the key
+//	remove <key>       deletes the key
+//	load <key>...      replaces the tree with a balanced one built from the ascending keys
+//	rebuild            rebalances the tree
+//
+// Blank lines and lines starting with # are ignored. After every command the tree is
+// printed, nodes are marked with + if inserted, * if recolored and ^ if re-parented, which
+// happens on rotations and when a removed node is replaced by its child or successor.
+//
+// Usage:
+//
+//	rbtree-viz [-dot] [-step] [-strings] [script]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alldroll/rbtree"
+)
+
+type intKey int
+
+func (k intKey) Less(other rbtree.Item) bool {
+	return k < other.(intKey)
+}
+
+type stringKey string
+
+func (k stringKey) Less(other rbtree.Item) bool {
+	return k < other.(stringKey)
+}
+
+// mark tells how a node has changed since the previous step.
+type mark string
+
+const (
+	unchanged  mark = ""
+	inserted   mark = "+"
+	recolored  mark = "*"
+	reparented mark = "^"
+)
+
+// placement is the state of a node used to detect changes between steps.
+type placement struct {
+	red    bool
+	parent string
+}
+
+func main() {
+	dot := flag.Bool("dot", false, "render Graphviz DOT instead of ASCII")
+	step := flag.Bool("step", false, "wait for Enter after each step")
+	stringKeys := flag.Bool("strings", false, "treat keys as strings instead of integers")
+	flag.Parse()
+
+	script := os.Stdin
+	if flag.NArg() > 0 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+
+		defer f.Close()
+		script = f
+	} else if *step {
+		fatal(fmt.Errorf("-step requires a script file, stdin is used for stepping"))
+	}
+
+	render := renderASCII
+	if *dot {
+		render = renderDOT
+	}
+
+	var pause io.Reader
+	if *step {
+		pause = os.Stdin
+	}
+
+	if err := run(script, pause, os.Stdout, parser(*stringKeys), render); err != nil {
+		fatal(err)
+	}
+}
+
+// parser returns a function that converts script arguments to keys.
+func parser(stringKeys bool) func(s string) (rbtree.Item, error) {
+	return func(s string) (rbtree.Item, error) {
+		if stringKeys {
+			return stringKey(s), nil
+		}
+
+		v, err := strconv.Atoi(s)
+		return intKey(v), err
+	}
+}
+
+// run executes the script and renders the tree after each command.
+// If pause is not nil, a line is read from it after each step.
+func run(
+	script io.Reader,
+	pause io.Reader,
+	out io.Writer,
+	parse func(s string) (rbtree.Item, error),
+	render func(w io.Writer, title string, root *rbtree.NodeInfo, marks map[string]mark),
+) error {
+	var stepper *bufio.Reader
+	if pause != nil {
+		stepper = bufio.NewReader(pause)
+	}
+
+	tree := rbtree.New()
+	prev := map[string]placement{}
+	scanner := bufio.NewScanner(script)
+	steps := 0
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		keys := make([]rbtree.Item, 0, len(fields)-1)
+		for _, s := range fields[1:] {
+			key, err := parse(s)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+
+			keys = append(keys, key)
+		}
+
+		var err error
+		tree, err = apply(tree, fields[0], keys)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+
+		steps++
+		root := rbtree.Structure(tree)
+		next := placements(root)
+		render(out, fmt.Sprintf("step %d: %s", steps, strings.Join(fields, " ")), root, diff(prev, next))
+		prev = next
+
+		if stepper != nil {
+			stepper.ReadString('\n')
+		}
+	}
+
+	return scanner.Err()
+}
+
+// apply executes a single command and returns the resulting tree.
+func apply(tree rbtree.Tree, command string, keys []rbtree.Item) (rbtree.Tree, error) {
+	switch command {
+	case "insert", "remove":
+		if len(keys) != 1 {
+			return nil, fmt.Errorf("%s expects a single key", command)
+		}
+
+		if command == "insert" {
+			tree.Insert(keys[0])
+		} else {
+			tree.Remove(keys[0])
+		}
+	case "load":
+		builder := rbtree.NewBuilder()
+		for _, key := range keys {
+			if err := builder.Add(key); err != nil {
+				return nil, err
+			}
+		}
+
+		tree = builder.Build()
+	case "rebuild":
+		tree.Rebuild()
+	default:
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+
+	return tree, nil
+}
+
+// placements collects the color and the parent of every node.
+func placements(root *rbtree.NodeInfo) map[string]placement {
+	result := map[string]placement{}
+
+	var walk func(n *rbtree.NodeInfo, parent string)
+	walk = func(n *rbtree.NodeInfo, parent string) {
+		if n == nil {
+			return
+		}
+
+		key := fmt.Sprint(n.Item)
+		result[key] = placement{n.Red, parent}
+		walk(n.Left, key)
+		walk(n.Right, key)
+	}
+
+	walk(root, "")
+	return result
+}
+
+// diff marks the nodes that have changed between two steps.
+func diff(prev, next map[string]placement) map[string]mark {
+	marks := map[string]mark{}
+
+	for key, p := range next {
+		old, ok := prev[key]
+		switch {
+		case !ok:
+			marks[key] = inserted
+		case old.parent != p.parent:
+			marks[key] = reparented
+		case old.red != p.red:
+			marks[key] = recolored
+		default:
+			marks[key] = unchanged
+		}
+	}
+
+	return marks
+}
+
+// renderASCII prints the tree rotated by 90 degrees, the right subtree goes first.
+func renderASCII(w io.Writer, title string, root *rbtree.NodeInfo, marks map[string]mark) {
+	fmt.Fprintf(w, "== %s ==\n", title)
+
+	var walk func(n *rbtree.NodeInfo, depth int)
+	walk = func(n *rbtree.NodeInfo, depth int) {
+		if n == nil {
+			return
+		}
+
+		walk(n.Right, depth+1)
+
+		key, color := fmt.Sprint(n.Item), "B"
+		if n.Red {
+			color = "R"
+		}
+
+		fmt.Fprintf(w, "%s%s(%s)%s\n", strings.Repeat("    ", depth), key, color, marks[key])
+		walk(n.Left, depth+1)
+	}
+
+	if root == nil {
+		fmt.Fprintln(w, "(empty)")
+	}
+
+	walk(root, 0)
+	fmt.Fprintln(w)
+}
+
+// renderDOT prints the tree as a Graphviz digraph, changed nodes get a thick outline.
+func renderDOT(w io.Writer, title string, root *rbtree.NodeInfo, marks map[string]mark) {
+	fmt.Fprintf(w, "digraph %q {\n", title)
+	fmt.Fprintln(w, "\tnode [style=filled, fontcolor=white];")
+
+	var walk func(n *rbtree.NodeInfo)
+	walk = func(n *rbtree.NodeInfo) {
+		if n == nil {
+			return
+		}
+
+		key, color := fmt.Sprint(n.Item), "black"
+		if n.Red {
+			color = "red"
+		}
+
+		penwidth := 1
+		if marks[key] != unchanged {
+			penwidth = 4
+		}
+
+		fmt.Fprintf(w, "\t%q [fillcolor=%s, color=gold, penwidth=%d, xlabel=%q];\n", key, color, penwidth, string(marks[key]))
+
+		for _, child := range []*rbtree.NodeInfo{n.Left, n.Right} {
+			if child != nil {
+				fmt.Fprintf(w, "\t%q -> %q;\n", key, fmt.Sprint(child.Item))
+				walk(child)
+			}
+		}
+	}
+
+	walk(root)
+	fmt.Fprintln(w, "}")
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "rbtree-viz:", err)
+	os.Exit(1)
+}
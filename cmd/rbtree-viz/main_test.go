@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunASCII(t *testing.T) {
+	script := `
+# Cormen 13.3.3 followed by a removal
+insert 41
+insert 38
+insert 31
+insert 12
+remove 38
+load 1 2 3
+`
+
+	expected := `== step 1: insert 41 ==
+41(B)+
+
+== step 2: insert 38 ==
+41(B)
+    38(R)+
+
+== step 3: insert 31 ==
+    41(R)^
+38(B)^
+    31(R)+
+
+== step 4: insert 12 ==
+    41(B)*
+38(B)
+    31(B)*
+        12(R)+
+
+== step 5: remove 38 ==
+    41(B)^
+31(B)^
+    12(B)*
+
+== step 6: load 1 2 3 ==
+    3(B)+
+2(B)+
+    1(B)+
+
+`
+
+	var out bytes.Buffer
+	if err := run(strings.NewReader(script), nil, &out, parser(false), renderASCII); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestRunDOT(t *testing.T) {
+	expected := `digraph "step 1: insert b" {
+	node [style=filled, fontcolor=white];
+	"b" [fillcolor=black, color=gold, penwidth=4, xlabel="+"];
+}
+digraph "step 2: insert a" {
+	node [style=filled, fontcolor=white];
+	"b" [fillcolor=black, color=gold, penwidth=1, xlabel=""];
+	"b" -> "a";
+	"a" [fillcolor=red, color=gold, penwidth=4, xlabel="+"];
+}
+`
+
+	var out bytes.Buffer
+	if err := run(strings.NewReader("insert b\ninsert a\n"), nil, &out, parser(true), renderDOT); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+// pauseReader answers every read with a newline and notes it in the output.
+type pauseReader struct {
+	out *bytes.Buffer
+}
+
+func (r pauseReader) Read(p []byte) (int, error) {
+	r.out.WriteString("<pause>\n")
+	p[0] = '\n'
+	return 1, nil
+}
+
+func TestRunStep(t *testing.T) {
+	expected := `== step 1: insert 2 ==
+2(B)+
+
+<pause>
+== step 2: insert 1 ==
+2(B)
+    1(R)+
+
+<pause>
+`
+
+	var out bytes.Buffer
+	if err := run(strings.NewReader("insert 2\n\ninsert 1\n"), pauseReader{&out}, &out, parser(false), renderASCII); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	cases := []struct {
+		script   string
+		expected string
+	}{
+		{"insert 1\nbogus 2\n", `line 2: unknown command "bogus"`},
+		{"insert x\n", `line 1: strconv.Atoi: parsing "x": invalid syntax`},
+		{"remove 1 2\n", "line 1: remove expects a single key"},
+		{"load 2 1\n", "line 1: Given item is less than the previously added one"},
+	}
+
+	for _, c := range cases {
+		var out bytes.Buffer
+		err := run(strings.NewReader(c.script), nil, &out, parser(false), renderASCII)
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("Expected error %q, got %v", c.expected, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package rbtree
+
+import (
+	"bytes"
+	"hash/fnv"
+)
+
+// Collation orders strings by sort keys, e.g. to get locale-aware ordering.
+// A golang.org/x/text/collate.Collator can be plugged in as follows:
+//
+//	var buf collate.Buffer
+//	c := rbtree.NewCollation(func(s string) []byte {
+//		key := append([]byte(nil), collator.KeyFromString(&buf, s)...)
+//		buf.Reset()
+//		return key
+//	})
+//
+// Neither the Collator nor the shared Buffer is safe for concurrent use,
+// so such a Collation must not make items from several goroutines at once.
+type Collation struct {
+	sortKey func(s string) []byte
+}
+
+// CollatedString is an Item that holds a string along with its cached sort key.
+// Strings with equal sort keys are equal items. Items made by different
+// collations must not be stored in the same tree.
+type CollatedString struct {
+	value string
+	key   []byte
+}
+
+// NewCollation returns a new instance of Collation that uses the given function
+// to compute sort keys. The function result is retained, so it must not reuse buffers.
+func NewCollation(sortKey func(s string) []byte) *Collation {
+	return &Collation{sortKey}
+}
+
+// Item returns the given string as an Item ordered by this collation.
+// The sort key is computed once and cached in the item.
+func (c *Collation) Item(s string) CollatedString {
+	return CollatedString{s, c.sortKey(s)}
+}
+
+// Less tells whether the current element is less than the given argument.
+func (cs CollatedString) Less(other Item) bool {
+	return bytes.Compare(cs.key, other.(CollatedString).key) < 0
+}
+
+// Hash returns the hash of the sort key, so collated strings can use the lookup filter.
+func (cs CollatedString) Hash() uint64 {
+	h := fnv.New64a()
+	h.Write(cs.key)
+
+	return h.Sum64()
+}
+
+// String returns the original string.
+func (cs CollatedString) String() string {
+	return cs.value
+}
+
+// SortKey returns the cached sort key.
+func (cs CollatedString) SortKey() []byte {
+	return cs.key
+}
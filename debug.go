@@ -0,0 +1,7 @@
+//go:build rbtreedebug
+
+package rbtree
+
+// debug enables runtime checks of the tree usage, build with -tags rbtreedebug.
+// The tree panics on concurrent access and on inconsistent Item.Less.
+const debug = true
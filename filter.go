@@ -0,0 +1,97 @@
+package rbtree
+
+import "math"
+
+// Hasher is an optional capability of an Item used by the lookup filter, see NewWithFilter.
+// Equal items must return equal hashes.
+type Hasher interface {
+	// Hash returns the hash of the current element.
+	Hash() uint64
+}
+
+// bloomFilter is a counting Bloom filter, which answers whether a hash
+// is definitely absent or possibly present, and supports removals.
+type bloomFilter struct {
+	counters []uint8
+	hashes   int
+}
+
+// newBloomFilter returns a filter sized for the given number of items and false positive rate.
+func newBloomFilter(capacity int, falsePositiveRate float64) *bloomFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+
+	n := float64(capacity)
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &bloomFilter{
+		counters: make([]uint8, int(m)),
+		hashes:   k,
+	}
+}
+
+// add registers the given hash in the filter.
+func (f *bloomFilter) add(h uint64) {
+	f.each(h, func(i uint64) {
+		if f.counters[i] < math.MaxUint8 {
+			f.counters[i]++
+		}
+	})
+}
+
+// remove unregisters the given hash from the filter.
+// Saturated counters are never decremented, so they can only cause false positives.
+func (f *bloomFilter) remove(h uint64) {
+	f.each(h, func(i uint64) {
+		if c := f.counters[i]; c > 0 && c < math.MaxUint8 {
+			f.counters[i]--
+		}
+	})
+}
+
+// mayContain returns false if the given hash is definitely absent in the filter.
+func (f *bloomFilter) mayContain(h uint64) bool {
+	step, m := mix(h)|1, uint64(len(f.counters))
+	for j := 0; j < f.hashes; j++ {
+		if f.counters[(h+uint64(j)*step)%m] == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reset unregisters all hashes.
+func (f *bloomFilter) reset() {
+	for i := range f.counters {
+		f.counters[i] = 0
+	}
+}
+
+// each calls fn for every counter position of the given hash using double hashing.
+func (f *bloomFilter) each(h uint64, fn func(i uint64)) {
+	step, m := mix(h)|1, uint64(len(f.counters))
+	for j := 0; j < f.hashes; j++ {
+		fn((h + uint64(j)*step) % m)
+	}
+}
+
+// mix is the splitmix64 finalizer, it derives the second hash for double hashing.
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+
+	return h
+}
@@ -19,11 +19,54 @@ type Tree interface {
 	Min() Item
 	// Returns the max element in the tree.
 	Max() Item
+	// Closest returns whichever of the greatest item less than or equal to the given one
+	// and the least item greater than or equal to it is nearer to the given item.
+	// Ties, and items that do not implement Distancer, resolve to the smaller candidate.
+	// Returns nil if the tree is empty.
+	Closest(item Item) Item
+	// Median returns the ⌈n/2⌉-th smallest item in the tree, or nil if the tree is empty.
+	Median() Item
+	// KSmallest returns up to k smallest items in ascending order.
+	KSmallest(k int) []Item
+	// KLargest returns up to k largest items in descending order.
+	KLargest(k int) []Item
 	// Returns an iterator that points at the smallest element in the tree.
 	NewIterator() Iterator
 	// SubTree returns a view of the portion of this tree whose keys range from
 	// fromKey, inclusive, to toKey, exclusive.
 	SubTree(fromKey Item, toKey Item) (Tree, error)
+	// Batch calls fn with a transaction over the tree. If fn returns an error or panics,
+	// all changes made through the transaction are rolled back. Watchers get the events
+	// of the batch only once it is committed, a rolled back batch produces no events.
+	Batch(fn func(tx Txn) error) error
+	// Watch subscribes to insertions, removals and replacements of items whose keys range from
+	// fromKey, inclusive, to toKey, exclusive. Events are buffered and mutations never block:
+	// if the buffer overflows, the subscription is dropped and the channel is closed, so the
+	// consumer should reload the range and watch again. The returned function cancels the
+	// subscription and closes the channel, it is safe to call from the consumer goroutine.
+	// Events of a Batch are delivered when it commits, see Batch.
+	Watch(fromKey, toKey Item) (<-chan ChangeEvent, func())
+	// Rebuild reconstructs the whole underlying tree into an optimally balanced shape.
+	// It also refreshes the lookup filter, if any. Iterators created before the call,
+	// including those held by IntersectIterator and UnionIterator, become invalid and
+	// must not be used afterwards.
+	Rebuild()
+	// Repair re-sorts and rebuilds the whole underlying tree if its items are found out of order,
+	// e.g. after Item.Less has been fixed. Returns true if the tree has been rebuilt, in which
+	// case outstanding iterators become invalid as after Rebuild. Items that turn out to be
+	// equal are collapsed into one, and watchers get an OpReplace event for each dropped item.
+	// Journal and WAL do not record the repair, so a new checkpoint should be taken after it.
+	Repair() bool
+}
+
+// Txn represents a set of changes applied to a tree within Tree.Batch.
+type Txn interface {
+	// Insert adds the given item to the tree, see Tree.Insert.
+	Insert(item Item) (bool, error)
+	// Remove deletes an item equals to the given item from the tree, see Tree.Remove.
+	Remove(item Item) (bool, error)
+	// Returns the item if the given key is in the tree, otherwise return nil.
+	Find(item Item) Item
 }
 
 // Item represents a single object in the tree.
@@ -32,6 +75,12 @@ type Item interface {
 	Less(other Item) bool
 }
 
+// Distancer is an optional capability of an Item used by Tree.Closest.
+type Distancer interface {
+	// Distance returns the non-negative distance between the current element and the given argument.
+	Distance(other Item) float64
+}
+
 // Iterator represents an iterator over a tree collection which provides inorder traverse.
 type Iterator interface {
 	// IsValid returns true if the iterator is valid, otherwise returns false.
@@ -10,8 +10,10 @@ const (
 
 // iterator implements Iterator interface for Tree collection.
 type iterator struct {
-	node  *node
-	state state
+	node     *node
+	state    state
+	tree     *rbTree
+	rebuilds uint32 // the tree rebuild count at the moment of creation
 }
 
 // IsValid returns true if the iterator is valid, otherwise returns false.
@@ -21,6 +23,12 @@ func (it *iterator) IsValid() bool {
 
 // Next moves the iterator to the next element and returns it.
 func (it *iterator) Next() Item {
+	if debug {
+		it.tree.beginRead()
+		defer it.tree.endRead()
+		it.checkStale()
+	}
+
 	if it.state == pastRear || it.node == tNil {
 		return nil
 	}
@@ -58,3 +66,40 @@ func (it *iterator) Get() Item {
 
 	return it.node.item
 }
+
+// seek moves the iterator to the least element greater than or equal to
+// the given item and returns it. The item must not be less than the current element.
+func (it *iterator) seek(item Item) Item {
+	if debug {
+		it.tree.beginRead()
+		defer it.tree.endRead()
+		it.checkStale()
+	}
+
+	if it.state == pastRear || it.node == tNil {
+		return nil
+	}
+
+	root := it.node
+	for root.parent != tNil {
+		root = root.parent
+	}
+
+	x := root.ceiling(item)
+	if x == tNil {
+		it.state = pastRear
+		return nil
+	}
+
+	it.node = x
+	it.state = deferencable
+	return x.item
+}
+
+// checkStale panics if the tree has been rebuilt since the iterator was created,
+// as the nodes the iterator points at are no longer linked into the tree.
+func (it *iterator) checkStale() {
+	if it.rebuilds != it.tree.rebuilds {
+		panic("rbtree: iterator used after the tree was rebuilt")
+	}
+}
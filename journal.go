@@ -0,0 +1,213 @@
+package rbtree
+
+import "errors"
+
+// OpKind is a kind of a tree mutation.
+type OpKind byte
+
+const (
+	// OpInsert is an insertion of a new item.
+	OpInsert OpKind = iota
+	// OpRemove is a removal of an item.
+	OpRemove
+	// OpReplace is an insertion of an item that replaced an equal one.
+	OpReplace
+)
+
+// Operation describes a single tree mutation.
+type Operation struct {
+	Kind OpKind
+	// Item is the inserted item, or the removed one for OpRemove.
+	Item Item
+	// Previous is the replaced item for OpReplace, otherwise nil.
+	Previous Item
+}
+
+// Journal is a Tree that records every mutation and allows to undo and redo them.
+// Mutations made through the underlying tree directly are not recorded.
+type Journal struct {
+	Tree
+	log *opLog
+}
+
+// opLog is a history of operations shared by a journal and its sub tree views.
+type opLog struct {
+	tree Tree
+	ops  []Operation
+	done int
+}
+
+// NewJournal returns a Journal that records mutations of the given tree.
+func NewJournal(tree Tree) *Journal {
+	return &Journal{tree, &opLog{tree: tree}}
+}
+
+// Insert adds the given item to the tree and records the operation, see Tree.Insert.
+func (j *Journal) Insert(item Item) (bool, error) {
+	old := j.Tree.Find(item)
+
+	ok, err := j.Tree.Insert(item)
+	if err != nil {
+		return ok, err
+	}
+
+	j.log.record(insertion(item, old))
+	return ok, nil
+}
+
+// Remove deletes an item equals to the given item from the tree and records the operation, see Tree.Remove.
+func (j *Journal) Remove(item Item) (bool, error) {
+	old := j.Tree.Find(item)
+
+	ok, err := j.Tree.Remove(item)
+	if ok {
+		j.log.record(Operation{Kind: OpRemove, Item: old})
+	}
+
+	return ok, err
+}
+
+// Batch calls fn with a transaction over the journal, see Tree.Batch.
+// A rolled back transaction leaves no trace in the log.
+// Errors of the rollback are joined to the returned error.
+func (j *Journal) Batch(fn func(tx Txn) error) error {
+	mark := j.log.done
+	release := holdEvents(j.Tree)
+
+	defer func() {
+		if r := recover(); r != nil {
+			release(j.log.rollback(mark) != nil)
+			panic(r)
+		}
+	}()
+
+	err := fn(j)
+	if err != nil {
+		rollbackErr := j.log.rollback(mark)
+		if rollbackErr != nil {
+			err = errors.Join(err, rollbackErr)
+		}
+
+		release(rollbackErr != nil)
+		return err
+	}
+
+	release(true)
+	return nil
+}
+
+// SubTree returns a journaled view of the portion of this tree whose keys range from
+// fromKey, inclusive, to toKey, exclusive. The view shares the log with this journal.
+func (j *Journal) SubTree(fromKey, toKey Item) (Tree, error) {
+	sub, err := j.Tree.SubTree(fromKey, toKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Journal{sub, j.log}, nil
+}
+
+// inRange returns true if the wrapped tree accepts the given key.
+func (j *Journal) inRange(item Item) bool {
+	return accepts(j.Tree, item)
+}
+
+// Undo reverts the last recorded operation.
+// Returns false if there is nothing to undo. If the operation cannot be reverted,
+// the error is returned and the operation stays recorded as done.
+func (j *Journal) Undo() (bool, error) {
+	if j.log.done == 0 {
+		return false, nil
+	}
+
+	if err := revert(j.log.tree, j.log.ops[j.log.done-1]); err != nil {
+		return false, err
+	}
+
+	j.log.done--
+	return true, nil
+}
+
+// Redo applies again the last undone operation.
+// Returns false if there is nothing to redo. If the operation cannot be applied,
+// the error is returned and the operation stays recorded as undone.
+func (j *Journal) Redo() (bool, error) {
+	if j.log.done == len(j.log.ops) {
+		return false, nil
+	}
+
+	if err := apply(j.log.tree, j.log.ops[j.log.done]); err != nil {
+		return false, err
+	}
+
+	j.log.done++
+	return true, nil
+}
+
+// Log returns the applied operations in chronological order.
+func (j *Journal) Log() []Operation {
+	return append([]Operation(nil), j.log.ops[:j.log.done]...)
+}
+
+// record appends the given operation and discards the undone ones.
+func (l *opLog) record(op Operation) {
+	l.ops = append(l.ops[:l.done], op)
+	l.done++
+}
+
+// rollback reverts operations recorded after the given position and discards them.
+// The undone history is kept if nothing has been recorded since then.
+// Returns the errors of the operations that could not be reverted.
+func (l *opLog) rollback(mark int) error {
+	if l.done == mark {
+		return nil
+	}
+
+	var errs []error
+	for l.done > mark {
+		l.done--
+		if err := revert(l.tree, l.ops[l.done]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	l.ops = l.ops[:mark]
+	return errors.Join(errs...)
+}
+
+// insertion returns an operation for the insertion of the given item over the previous one, if any.
+func insertion(item, previous Item) Operation {
+	if previous == nil {
+		return Operation{Kind: OpInsert, Item: item}
+	}
+
+	return Operation{Kind: OpReplace, Item: item, Previous: previous}
+}
+
+// apply performs the given operation on the target.
+func apply(target Txn, op Operation) error {
+	var err error
+	if op.Kind == OpRemove {
+		_, err = target.Remove(op.Item)
+	} else {
+		_, err = target.Insert(op.Item)
+	}
+
+	return err
+}
+
+// revert performs the inverse of the given operation on the target.
+func revert(target Txn, op Operation) error {
+	var err error
+
+	switch op.Kind {
+	case OpInsert:
+		_, err = target.Remove(op.Item)
+	case OpRemove:
+		_, err = target.Insert(op.Item)
+	case OpReplace:
+		_, err = target.Insert(op.Previous)
+	}
+
+	return err
+}
@@ -0,0 +1,146 @@
+package rbtree
+
+// MultiIndex keeps the same set of values in several trees ordered by
+// different comparators, e.g. by ID and by score. Indexes are identified by
+// the position of their comparator passed to NewMultiIndex.
+// Index 0 is the primary key: values are unique by it, and inserting a value
+// replaces the one with an equal primary key. Other indexes may hold values
+// with equal keys, their ties are broken by the primary comparator.
+type MultiIndex[T any] struct {
+	indexes []*rbTree
+	less    []func(a, b T) bool
+	order   []func(a, b T) bool
+}
+
+// indexItem adapts a value to the Item interface with the ordering of an index.
+type indexItem[T any] struct {
+	value T
+	less  func(a, b T) bool
+}
+
+// Less tells whether the current element is less than the given argument.
+func (it indexItem[T]) Less(other Item) bool {
+	return it.less(it.value, other.(indexItem[T]).value)
+}
+
+// NewMultiIndex returns a new instance of MultiIndex with an index for each given comparator.
+// The first comparator defines the primary key.
+func NewMultiIndex[T any](less ...func(a, b T) bool) *MultiIndex[T] {
+	m := &MultiIndex[T]{
+		indexes: make([]*rbTree, len(less)),
+		less:    less,
+		order:   make([]func(a, b T) bool, len(less)),
+	}
+
+	for i := range less {
+		m.indexes[i] = New().(*rbTree)
+		m.order[i] = less[i]
+
+		if i > 0 {
+			key, primary := less[i], less[0]
+			m.order[i] = func(a, b T) bool {
+				return key(a, b) || !key(b, a) && primary(a, b)
+			}
+		}
+	}
+
+	return m
+}
+
+// Len returns the number of values in the container.
+func (m *MultiIndex[T]) Len() int {
+	if len(m.indexes) == 0 {
+		return 0
+	}
+
+	return m.indexes[0].Len()
+}
+
+// Insert adds the given value to all indexes, replacing the value with an equal primary key.
+// Returns true if no value was replaced.
+func (m *MultiIndex[T]) Insert(v T) bool {
+	if len(m.indexes) == 0 {
+		return false
+	}
+
+	old := m.lookup(0, v)
+	if old != tNil {
+		m.remove(old.item.(indexItem[T]).value)
+	}
+
+	for i, index := range m.indexes {
+		index.Insert(m.item(i, v))
+	}
+
+	return old == tNil
+}
+
+// Remove deletes a value whose key equals to the given one in the given index from all indexes.
+// For secondary indexes the value with the least primary key among equal ones is removed.
+// Returns true if a value was removed, otherwise returns false.
+func (m *MultiIndex[T]) Remove(index int, key T) bool {
+	found := m.lookup(index, key)
+	if found == tNil {
+		return false
+	}
+
+	m.remove(found.item.(indexItem[T]).value)
+	return true
+}
+
+// Find returns a value whose key equals to the given one in the given index, or false if there is no such value.
+// For secondary indexes the value with the least primary key among equal ones is returned.
+func (m *MultiIndex[T]) Find(index int, key T) (T, bool) {
+	found := m.lookup(index, key)
+	if found == tNil {
+		var zero T
+		return zero, false
+	}
+
+	return found.item.(indexItem[T]).value, true
+}
+
+// Ascend calls fn for each value in the ascending order of the given index until fn returns false.
+func (m *MultiIndex[T]) Ascend(index int, fn func(v T) bool) {
+	iter := m.indexes[index].NewIterator()
+	for item := iter.Next(); item != nil; item = iter.Next() {
+		if !fn(item.(indexItem[T]).value) {
+			return
+		}
+	}
+}
+
+// lookup returns the leftmost node whose key equals to the given one by the comparator
+// of the given index, otherwise returns tNil. The index ordering refines the comparator,
+// so nodes with equal keys are adjacent.
+func (m *MultiIndex[T]) lookup(index int, key T) *node {
+	less := m.less[index]
+	x, found := m.indexes[index].root, tNil
+
+	for x != tNil {
+		v := x.item.(indexItem[T]).value
+		if less(v, key) {
+			x = x.right
+		} else {
+			if !less(key, v) {
+				found = x
+			}
+
+			x = x.left
+		}
+	}
+
+	return found
+}
+
+// remove deletes the given stored value from all indexes.
+func (m *MultiIndex[T]) remove(v T) {
+	for i, index := range m.indexes {
+		index.Remove(m.item(i, v))
+	}
+}
+
+// item wraps the given value with the ordering of the given index.
+func (m *MultiIndex[T]) item(index int, v T) indexItem[T] {
+	return indexItem[T]{v, m.order[index]}
+}
@@ -35,6 +35,34 @@ func (nd *node) max() *node {
 	return n
 }
 
+// Returns the successor of this node, or tNil if this node is the max one.
+func (nd *node) next() *node {
+	if nd.right != tNil {
+		return nd.right.min()
+	}
+
+	x, y := nd, nd.parent
+	for y != tNil && x == y.right {
+		x, y = y, y.parent
+	}
+
+	return y
+}
+
+// Returns the predecessor of this node, or tNil if this node is the min one.
+func (nd *node) prev() *node {
+	if nd.left != tNil {
+		return nd.left.max()
+	}
+
+	x, y := nd, nd.parent
+	for y != tNil && x == y.left {
+		x, y = y, y.parent
+	}
+
+	return y
+}
+
 // Inspired by java.util.TreeMap#getCeilingEntry
 // Gets the node corresponding to the specified item; if no such node
 // exists, returns the node for the least item greater than the specified
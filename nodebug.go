@@ -0,0 +1,6 @@
+//go:build !rbtreedebug
+
+package rbtree
+
+// debug enables runtime checks of the tree usage, build with -tags rbtreedebug.
+const debug = false
@@ -0,0 +1,78 @@
+package rbtree
+
+import "cmp"
+
+// OrderedTree is a tree of values of a primitive ordered type, which
+// handles the comparison internally, so no Item wrapper is needed.
+type OrderedTree[T cmp.Ordered] struct {
+	tree Tree
+}
+
+// orderedItem adapts a value of an ordered type to the Item interface.
+type orderedItem[T cmp.Ordered] struct {
+	value T
+}
+
+// Less tells whether the current element is less than the given argument.
+func (it orderedItem[T]) Less(other Item) bool {
+	return cmp.Less(it.value, other.(orderedItem[T]).value)
+}
+
+// NewOrdered returns a new instance of OrderedTree, e.g. NewOrdered[int]().
+func NewOrdered[T cmp.Ordered]() *OrderedTree[T] {
+	return &OrderedTree[T]{New()}
+}
+
+// Len returns the number of values in the tree.
+func (ot *OrderedTree[T]) Len() int {
+	return ot.tree.Len()
+}
+
+// Insert adds the given value to the tree.
+// Returns true if the value was inserted, or false if it was already present.
+func (ot *OrderedTree[T]) Insert(v T) bool {
+	ok, _ := ot.tree.Insert(orderedItem[T]{v})
+	return ok
+}
+
+// Remove deletes the given value from the tree.
+// Returns true if the value was removed, otherwise returns false.
+func (ot *OrderedTree[T]) Remove(v T) bool {
+	ok, _ := ot.tree.Remove(orderedItem[T]{v})
+	return ok
+}
+
+// Contains returns true if the given value is in the tree.
+func (ot *OrderedTree[T]) Contains(v T) bool {
+	return ot.tree.Find(orderedItem[T]{v}) != nil
+}
+
+// Min returns the min value in the tree, or false if the tree is empty.
+func (ot *OrderedTree[T]) Min() (T, bool) {
+	return ot.value(ot.tree.KSmallest(1))
+}
+
+// Max returns the max value in the tree, or false if the tree is empty.
+func (ot *OrderedTree[T]) Max() (T, bool) {
+	return ot.value(ot.tree.KLargest(1))
+}
+
+// Ascend calls fn for each value in ascending order until fn returns false.
+func (ot *OrderedTree[T]) Ascend(fn func(v T) bool) {
+	iter := ot.tree.NewIterator()
+	for item := iter.Next(); item != nil; item = iter.Next() {
+		if !fn(item.(orderedItem[T]).value) {
+			return
+		}
+	}
+}
+
+// value unwraps the first of the given items.
+func (ot *OrderedTree[T]) value(items []Item) (T, bool) {
+	if len(items) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return items[0].(orderedItem[T]).value, true
+}
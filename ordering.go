@@ -0,0 +1,90 @@
+package rbtree
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrorInconsistentOrdering tells that Item.Less is not a strict weak ordering.
+// In debug mode operations panic with this error once an inconsistency is observed.
+var ErrorInconsistentOrdering error = errors.New("Item.Less is not a strict weak ordering")
+
+// checkAsymmetry panics if a < b and b < a at the same time.
+func checkAsymmetry(a, b Item) {
+	if a.Less(b) && b.Less(a) {
+		panic(ErrorInconsistentOrdering)
+	}
+}
+
+// checkNeighbours panics if the given node is not strictly between its neighbours,
+// or if the ordering of the neighbours is not transitive.
+func checkNeighbours(z *node) {
+	prev, next := z.prev(), z.next()
+
+	if prev != tNil && (!prev.item.Less(z.item) || z.item.Less(prev.item)) {
+		panic(ErrorInconsistentOrdering)
+	}
+
+	if next != tNil && (!z.item.Less(next.item) || next.item.Less(z.item)) {
+		panic(ErrorInconsistentOrdering)
+	}
+
+	if prev != tNil && next != tNil && (!prev.item.Less(next.item) || next.item.Less(prev.item)) {
+		panic(ErrorInconsistentOrdering)
+	}
+}
+
+// Repair re-sorts the items and rebuilds the tree if their in-order sequence
+// is not strictly ascending. Items that turn out to be equal are collapsed into
+// the one that comes last, watchers get an OpReplace event for each dropped item.
+// Returns true if the tree has been rebuilt.
+func (rb *rbTree) Repair() bool {
+	if debug {
+		rb.beginWrite()
+		defer rb.endWrite()
+	}
+
+	items := rb.items()
+	if isStrictlyAscending(items) {
+		return false
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Less(items[j])
+	})
+
+	var replaced []Operation
+	unique := make([]Item, 0, len(items))
+	for i := 0; i < len(items); {
+		j := i + 1
+		for j < len(items) && !items[j-1].Less(items[j]) {
+			j++
+		}
+
+		last := items[j-1]
+		for _, item := range items[i : j-1] {
+			replaced = append(replaced, Operation{Kind: OpReplace, Item: last, Previous: item})
+		}
+
+		unique = append(unique, last)
+		i = j
+	}
+
+	rb.load(unique)
+	for _, op := range replaced {
+		rb.notify(op)
+	}
+
+	return true
+}
+
+// isStrictlyAscending returns true if every item is less than the following one.
+func isStrictlyAscending(items []Item) bool {
+	for i := 1; i < len(items); i++ {
+		if !items[i-1].Less(items[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,72 @@
+package rbtree
+
+import "errors"
+
+// ErrorUnsupportedTree tells that the given Tree implementation is not supported by the operation.
+var ErrorUnsupportedTree error = errors.New("Given tree implementation is not supported")
+
+// PrefixRange returns a view of the portion of the string-keyed tree whose keys start
+// with the given prefix. The key function converts a string to the tree's Item type.
+// Trees returned by New, SubTree, PrefixRange, NewJournal and NewWAL are supported.
+func PrefixRange(tree Tree, prefix string, key func(s string) Item) (Tree, error) {
+	view := &subTree{fromKey: key(prefix), exclusive: true}
+	if upper, ok := prefixSuccessor(prefix); ok {
+		view.toKey = key(upper)
+	}
+
+	switch t := tree.(type) {
+	case *rbTree:
+		view.tree = t
+	case *subTree:
+		view.tree = t.tree
+		view.intersect(t)
+	case *Journal:
+		sub, err := PrefixRange(t.Tree, prefix, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Journal{sub, t.log}, nil
+	case *WAL:
+		sub, err := PrefixRange(t.Tree, prefix, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return &WAL{sub, t.log}, nil
+	default:
+		return nil, ErrorUnsupportedTree
+	}
+
+	return view, nil
+}
+
+// prefixSuccessor returns the least string that is greater than every string with
+// the given prefix, or false if there is no such string (the prefix is empty or
+// consists of 0xFF bytes only).
+func prefixSuccessor(prefix string) (string, bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1]), true
+		}
+	}
+
+	return "", false
+}
+
+// intersect narrows the range of the subTree to its intersection with the other one.
+func (st *subTree) intersect(other *subTree) {
+	if st.fromKey.Less(other.fromKey) {
+		st.fromKey = other.fromKey
+	}
+
+	switch {
+	case other.toKey == nil:
+	case st.toKey == nil || other.toKey.Less(st.toKey):
+		st.toKey, st.exclusive = other.toKey, other.exclusive
+	case !st.toKey.Less(other.toKey):
+		st.exclusive = st.exclusive || other.exclusive
+	}
+}
@@ -1,23 +1,47 @@
 package rbtree
 
-import "errors"
+import (
+	"errors"
+	"sync/atomic"
+)
 
 // ErrorFromGreaterThanToKey informs that the fromKey should be less or equal to toKey
 var ErrorFromGreaterThanToKey error = errors.New("fromKey should be >= toKey")
 
 // rBTree is an implementation of red-black tree.
 type rbTree struct {
-	root   *node
-	length int
+	root     *node
+	length   int
+	median   *node // finger to the ⌈n/2⌉-th node, maintained on each update
+	filter   *bloomFilter
+	watchers watchList
+	access   atomic.Int32 // detects concurrent misuse in debug mode
+	rebuilds uint32       // counts load calls to detect stale iterators in debug mode
 }
 
 // New returns a new instance of Tree.
 func New() Tree {
-	return &rbTree{tNil, 0}
+	return &rbTree{root: tNil, median: tNil}
+}
+
+// NewWithFilter returns a new instance of Tree that maintains a probabilistic filter
+// alongside the tree, so Find for absent items usually returns without a descent.
+// The filter is sized for the given capacity and false positive rate, and it applies
+// only to items that implement Hasher.
+func NewWithFilter(capacity int, falsePositiveRate float64) Tree {
+	rb := New().(*rbTree)
+	rb.filter = newBloomFilter(capacity, falsePositiveRate)
+
+	return rb
 }
 
 // Returns the number of items in the tree.
 func (rb *rbTree) Len() int {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
 	return rb.length
 }
 
@@ -25,15 +49,27 @@ func (rb *rbTree) Len() int {
 // Returns true if the item was successfully inserted, or returns false if the item was replaced.
 // Returns an error if there was an attempt to add an element out of subtree range.
 func (rb *rbTree) Insert(item Item) (bool, error) {
+	if debug {
+		rb.beginWrite()
+		defer rb.endWrite()
+	}
+
 	z := &node{red, item, tNil, tNil, tNil}
-	res := rb.insert(z)
+	res, old := rb.insert(z)
 	result := false
 
 	if res == z { // if we insert z
 		rb.length++
+		rb.medianInserted(z)
+		rb.filterAdd(item)
 		result = true
+
+		if debug {
+			checkNeighbours(z)
+		}
 	}
 
+	rb.notify(insertion(item, old))
 	return result, nil
 }
 
@@ -41,18 +77,35 @@ func (rb *rbTree) Insert(item Item) (bool, error) {
 // Returns true if the item was successfully removes, otherwise returns false.
 // Returns an error if there was an attempt to remove an element out of subtree range.
 func (rb *rbTree) Remove(item Item) (bool, error) {
+	if debug {
+		rb.beginWrite()
+		defer rb.endWrite()
+	}
+
 	z, _ := rb.find(item)
 	if z == tNil {
 		return false, nil
 	}
 
+	rb.filterRemove(z.item)
+	rb.medianRemoved(z)
 	rb.remove(z)
 	rb.length--
+	rb.notify(Operation{Kind: OpRemove, Item: z.item})
 	return true, nil
 }
 
 // Returns a item if the given key is in the tree, otherwise return nil.
 func (rb *rbTree) Find(item Item) Item {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
+	if h, ok := item.(Hasher); ok && rb.filter != nil && !rb.filter.mayContain(h.Hash()) {
+		return nil
+	}
+
 	x, _ := rb.find(item)
 	if x == tNil {
 		return nil
@@ -63,21 +116,88 @@ func (rb *rbTree) Find(item Item) Item {
 
 // Returns the min element in the tree
 func (rb *rbTree) Min() Item {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
 	return rb.root.min().item
 }
 
 // Returns the max element in the tree
 func (rb *rbTree) Max() Item {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
 	return rb.root.max().item
 }
 
+// Closest returns the item nearest to the given one, see Tree.Closest.
+func (rb *rbTree) Closest(item Item) Item {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
+	return closest(item, rb.root.floor(item), rb.root.ceiling(item))
+}
+
+// Median returns the ⌈n/2⌉-th smallest item in the tree in O(1), or nil if the tree is empty.
+func (rb *rbTree) Median() Item {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
+	if rb.median == tNil {
+		return nil
+	}
+
+	return rb.median.item
+}
+
+// KSmallest returns up to k smallest items in ascending order.
+func (rb *rbTree) KSmallest(k int) []Item {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
+	if rb.root == tNil {
+		return []Item{}
+	}
+
+	return collect(rb.root.min(), rb.clamp(k), (*node).next, nil)
+}
+
+// KLargest returns up to k largest items in descending order.
+func (rb *rbTree) KLargest(k int) []Item {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
+	if rb.root == tNil {
+		return []Item{}
+	}
+
+	return collect(rb.root.max(), rb.clamp(k), (*node).prev, nil)
+}
+
 // Returns an iterator that points at the smallest element in the tree.
 func (rb *rbTree) NewIterator() Iterator {
+	if debug {
+		rb.beginRead()
+		defer rb.endRead()
+	}
+
 	if rb.Len() == 0 {
-		return &iterator{tNil, beforeFirst}
+		return &iterator{tNil, beforeFirst, rb, rb.rebuilds}
 	}
 
-	return &iterator{rb.root.min(), beforeFirst}
+	return &iterator{rb.root.min(), beforeFirst, rb, rb.rebuilds}
 }
 
 // SubTree returns a view of the portion of this tree whose keys range from
@@ -94,12 +214,155 @@ func (rb *rbTree) SubTree(fromKey, toKey Item) (Tree, error) {
 	}, nil
 }
 
+// Batch calls fn with a transaction over the tree, see Tree.Batch.
+func (rb *rbTree) Batch(fn func(tx Txn) error) error {
+	return batch(rb, fn)
+}
+
+// Watch subscribes to changes of items whose keys range from fromKey to toKey, see Tree.Watch.
+func (rb *rbTree) Watch(fromKey, toKey Item) (<-chan ChangeEvent, func()) {
+	return rb.watch(func(item Item) bool {
+		return !item.Less(fromKey) && item.Less(toKey)
+	})
+}
+
+// inRange returns true, the tree accepts any key.
+func (rb *rbTree) inRange(item Item) bool {
+	return true
+}
+
+// Rebuild reconstructs the tree into an optimally balanced shape in O(n).
+func (rb *rbTree) Rebuild() {
+	if debug {
+		rb.beginWrite()
+		defer rb.endWrite()
+	}
+
+	rb.load(rb.items())
+}
+
+// items returns all items of the tree in ascending order.
+func (rb *rbTree) items() []Item {
+	items := make([]Item, 0, rb.length)
+	if rb.root == tNil {
+		return items
+	}
+
+	for x := rb.root.min(); x != tNil; x = x.next() {
+		items = append(items, x.item)
+	}
+
+	return items
+}
+
+// closest chooses the nearest to the given item among its floor and ceiling nodes.
+func closest(item Item, floor, ceiling *node) Item {
+	if floor == tNil && ceiling == tNil {
+		return nil
+	}
+
+	if floor == tNil {
+		return ceiling.item
+	}
+
+	if ceiling == tNil || floor == ceiling {
+		return floor.item
+	}
+
+	if d, ok := item.(Distancer); ok && d.Distance(ceiling.item) < d.Distance(floor.item) {
+		return ceiling.item
+	}
+
+	return floor.item
+}
+
+// clamp limits the given number of items to the range [0, Len()].
+func (rb *rbTree) clamp(k int) int {
+	if k < 0 {
+		return 0
+	}
+
+	if k > rb.length {
+		return rb.length
+	}
+
+	return k
+}
+
+// collect returns up to k items starting from the given node and following the step function.
+// The walk stops at tNil or at the first item rejected by the accept function, if any.
+func collect(x *node, k int, step func(*node) *node, accept func(Item) bool) []Item {
+	items := make([]Item, 0, k)
+	for ; x != tNil && len(items) < k; x = step(x) {
+		if accept != nil && !accept(x.item) {
+			break
+		}
+
+		items = append(items, x.item)
+	}
+
+	return items
+}
+
+// medianInserted moves the median finger after the insertion of the given node.
+// Must be called after the length has been increased.
+func (rb *rbTree) medianInserted(z *node) {
+	if rb.length == 1 {
+		rb.median = z
+		return
+	}
+
+	if z.item.Less(rb.median.item) {
+		if rb.length%2 == 0 {
+			rb.median = rb.median.prev()
+		}
+	} else if rb.length%2 == 1 {
+		rb.median = rb.median.next()
+	}
+}
+
+// medianRemoved moves the median finger before the removal of the given node.
+// Must be called while the node and the length are still intact.
+func (rb *rbTree) medianRemoved(z *node) {
+	odd := rb.length%2 == 1
+
+	if z == rb.median {
+		if odd {
+			rb.median = z.prev()
+		} else {
+			rb.median = z.next()
+		}
+	} else if z.item.Less(rb.median.item) {
+		if !odd {
+			rb.median = rb.median.next()
+		}
+	} else if odd {
+		rb.median = rb.median.prev()
+	}
+}
+
+// filterAdd registers the given item in the lookup filter, if any.
+func (rb *rbTree) filterAdd(item Item) {
+	if h, ok := item.(Hasher); ok && rb.filter != nil {
+		rb.filter.add(h.Hash())
+	}
+}
+
+// filterRemove unregisters the given item from the lookup filter, if any.
+func (rb *rbTree) filterRemove(item Item) {
+	if h, ok := item.(Hasher); ok && rb.filter != nil {
+		rb.filter.remove(h.Hash())
+	}
+}
+
 // insert adds the given node in the tree.
-func (rb *rbTree) insert(z *node) *node {
+// Returns the node holding the item and the replaced item, if any.
+func (rb *rbTree) insert(z *node) (*node, Item) {
 	x, y := rb.find(z.item)
 	if x != tNil {
+		old := x.item
 		x.item = z.item
-		return x
+		return x, old
 	}
 
 	z.parent = y
@@ -116,7 +379,7 @@ func (rb *rbTree) insert(z *node) *node {
 	z.right = tNil
 
 	rb.insertFixup(z)
-	return z
+	return z, nil
 }
 
 // remove deletes the given node from the tree.
@@ -159,6 +422,10 @@ func (rb *rbTree) find(item Item) (*node, *node) {
 	y := tNil
 
 	for x != tNil {
+		if debug {
+			checkAsymmetry(item, x.item)
+		}
+
 		if item.Less(x.item) {
 			y, x = x, x.left
 		} else if x.item.Less(item) {
@@ -283,7 +550,7 @@ func (rb *rbTree) removeFixup(x *node) {
 					w.left.color = black
 					w.color = red
 					rb.rightRotate(w)
-					x = x.parent
+					w = x.parent.right
 				}
 				// case 4
 				w.color = x.parent.color
@@ -312,7 +579,7 @@ func (rb *rbTree) removeFixup(x *node) {
 					w.right.color = black
 					w.color = red
 					rb.leftRotate(w)
-					x = x.parent
+					w = x.parent.left
 				}
 				// case 4
 				w.color = x.parent.color
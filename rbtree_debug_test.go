@@ -0,0 +1,141 @@
+//go:build rbtreedebug
+
+package rbtree
+
+import (
+	"sync"
+	"testing"
+)
+
+// gate lets a test pause an operation inside Item.Less.
+type gate struct {
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newGate() *gate {
+	return &gate{entered: make(chan struct{}), release: make(chan struct{})}
+}
+
+// gateItem blocks in Less on its gate, if any, until the gate is released.
+type gateItem struct {
+	key  int
+	gate *gate
+}
+
+func (el gateItem) Less(other Item) bool {
+	if g := el.gate; g != nil {
+		g.once.Do(func() { close(g.entered) })
+		<-g.release
+	}
+
+	return el.key < other.(gateItem).key
+}
+
+func assertPanics(t *testing.T, name string, fn func()) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected %s to panic", name)
+		}
+	}()
+
+	fn()
+}
+
+func TestConcurrentAccessDetection(t *testing.T) {
+	tree := New().(*rbTree)
+	tree.beginRead()
+	tree.beginRead()
+	assertPanics(t, "write during read", tree.beginWrite)
+	tree.endRead()
+	tree.endRead()
+
+	tree.beginWrite()
+	assertPanics(t, "read during write", tree.beginRead)
+	assertPanics(t, "write during write", tree.beginWrite)
+	tree.endWrite()
+
+	tree.beginWrite()
+	tree.endWrite()
+	tree.beginRead()
+	tree.endRead()
+}
+
+func TestConcurrentWriteDetection(t *testing.T) {
+	tree := New()
+	for _, key := range []int{1, 3, 5} {
+		tree.Insert(gateItem{key: key})
+	}
+
+	iter := tree.NewIterator()
+	iter.Next()
+
+	g := newGate()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tree.Insert(gateItem{4, g})
+	}()
+
+	<-g.entered
+	assertPanics(t, "Find during Insert", func() { tree.Find(gateItem{key: 1}) })
+	assertPanics(t, "Insert during Insert", func() { tree.Insert(gateItem{key: 2}) })
+	assertPanics(t, "Len during Insert", func() { tree.Len() })
+	assertPanics(t, "iteration during Insert", func() { iter.Next() })
+	close(g.release)
+	<-done
+
+	assertEqualItems(t, gateItem{key: 3}, iter.Next())
+}
+
+func TestConcurrentReadDetection(t *testing.T) {
+	tree := New()
+	for _, key := range []int{1, 3, 5} {
+		tree.Insert(gateItem{key: key})
+	}
+
+	subTree, _ := tree.SubTree(gateItem{key: 0}, gateItem{key: 10})
+
+	g := newGate()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		subTree.Closest(gateItem{4, g})
+	}()
+
+	<-g.entered
+	assertPanics(t, "Insert during Closest", func() { tree.Insert(gateItem{key: 2}) })
+	assertPanics(t, "Remove during Closest", func() { tree.Remove(gateItem{key: 1}) })
+	tree.Find(gateItem{key: 1})
+	close(g.release)
+	<-done
+
+	tree.Insert(gateItem{key: 2})
+	if tree.Len() != 4 {
+		t.Errorf("Expected tree length to be 4, got %d", tree.Len())
+	}
+}
+
+func TestStaleIteratorDetection(t *testing.T) {
+	tree := New()
+	for _, v := range perm(10) {
+		tree.Insert(v)
+	}
+
+	other := New()
+	other.Insert(IntItem(3))
+
+	iter := tree.NewIterator()
+	iter.Next()
+	subTree, _ := tree.SubTree(IntItem(2), IntItem(8))
+	subIter := subTree.NewIterator()
+	intersect := IntersectIterator(tree, other)
+
+	tree.Rebuild()
+	assertPanics(t, "Next after Rebuild", func() { iter.Next() })
+	assertPanics(t, "sub tree Next after Rebuild", func() { subIter.Next() })
+	assertPanics(t, "IntersectIterator after Rebuild", func() { intersect.Next() })
+
+	assertEqualItems(t, IntItem(0), tree.NewIterator().Next())
+}
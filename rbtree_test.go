@@ -2,8 +2,14 @@
 package rbtree
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
 	"math/rand"
 	"sort"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -16,6 +22,14 @@ func (el IntItem) Less(other Item) bool {
 	return el < other.(IntItem)
 }
 
+func (el IntItem) Distance(other Item) float64 {
+	return math.Abs(float64(el - other.(IntItem)))
+}
+
+func (el IntItem) Hash() uint64 {
+	return uint64(el) * 0x9e3779b97f4a7c15
+}
+
 type StringItem string
 
 func (el StringItem) Less(other Item) bool {
@@ -44,7 +58,7 @@ func TestRotate(t *testing.T) {
 	a.parent = x
 	root.parent = tNil
 
-	tree := &rbTree{root, 0}
+	tree := &rbTree{root: root}
 
 	tree.leftRotate(x)
 	if root.left != y {
@@ -215,8 +229,34 @@ func TestLength(t *testing.T) {
 	if len(seq) != tree.Len() {
 		t.Errorf("Expected tree length to be %d, got %d", len(seq), tree.Len())
 	}
+}
+
+func TestRemoveFixup(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+
+	for round := 0; round < 20; round++ {
+		tree := New()
+		expected := make([]int, 0)
+		for _, v := range r.Perm(300) {
+			tree.Insert(IntItem(v))
+			expected = insertSorted(expected, v)
+		}
+
+		for _, v := range r.Perm(300) {
+			ok, _ := tree.Remove(IntItem(v))
+			if !ok {
+				t.Fatalf("Expected %d to be removed", v)
+			}
+
+			expected = removeSorted(expected, v)
+			assertRBProperties(t, tree)
+		}
 
-	// TODO test removeFixup
+		assertEqualIntDataset(t, tree, expected)
+		if tree.Len() != 0 {
+			t.Errorf("Expected tree length to be 0, got %d", tree.Len())
+		}
+	}
 }
 
 func TestIterator(t *testing.T) {
@@ -291,183 +331,1458 @@ func TestSubTree(t *testing.T) {
 	assertEqualIntDataset(t, subTree, expected)
 }
 
-var letters = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+func TestBuilder(t *testing.T) {
+	for n := 0; n < 64; n++ {
+		builder := NewBuilder()
+		expected := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			if err := builder.Add(IntItem(i)); err != nil {
+				t.Errorf("Unexpected error %v", err)
+			}
 
-func randString(n int) string {
-	b := make([]byte, n)
-	m := len(letters)
+			expected = append(expected, i)
+		}
 
-	for i := range b {
-		b[i] = letters[rand.Intn(m)]
+		tree := builder.Build()
+		assertEqualIntDataset(t, tree, expected)
+		assertRBProperties(t, tree)
+
+		if tree.Len() != n {
+			t.Errorf("Expected tree length to be %d, got %d", n, tree.Len())
+		}
 	}
 
-	return string(b)
-}
+	builder := NewBuilder()
+	builder.Add(IntItem(1))
+	builder.Add(IntItem(5))
+	builder.Add(IntItem(5))
 
-func assertEqualItems(t *testing.T, a, b Item) {
-	if (a != b) || a.Less(b) || b.Less(a) {
-		t.Errorf("Expected %d, got %d", a, b)
+	if err := builder.Add(IntItem(3)); err != ErrorItemOutOfOrder {
+		t.Errorf("Expected error %v, got %v", ErrorItemOutOfOrder, err)
 	}
-}
 
-func assertEqualIntDataset(t *testing.T, tree Tree, dataset []int) {
-	i := 0
+	assertEqualIntDataset(t, builder.Build(), []int{1, 5})
+}
 
-	iter := tree.NewIterator()
-	for {
-		val := iter.Next()
-		if val == nil {
-			break
+func TestRebuild(t *testing.T) {
+	tree := New()
+	expected := make([]int, 0)
+	for i := 0; i < 1024; i++ {
+		tree.Insert(IntItem(i))
+		if i%3 == 0 {
+			expected = append(expected, i)
 		}
+	}
 
-		if IntItem(dataset[i]) != val {
-			t.Errorf("Expected at {%d} to be %d, got %d", i, dataset[i], val)
+	for i := 0; i < 1024; i++ {
+		if i%3 != 0 {
+			tree.Remove(IntItem(i))
 		}
-
-		i++
 	}
 
-	if i != len(dataset) {
-		t.Errorf("Expected to iterate {%d}, got %d", len(dataset), i)
+	tree.Rebuild()
+	assertEqualIntDataset(t, tree, expected)
+	assertRBProperties(t, tree)
+
+	// 342 items fit into 9 levels
+	if h := height(tree.(*rbTree).root); h != 9 {
+		t.Errorf("Expected tree height to be 9, got %d", h)
 	}
 }
 
-func BenchmarkExistsString(b *testing.B) {
-	b.StopTimer()
+func TestKSmallestKLargest(t *testing.T) {
+	tree := New()
+	if items := tree.KSmallest(3); len(items) != 0 {
+		t.Errorf("Expected no items, got %v", items)
+	}
 
-	n := 10
-	vals := make([]StringItem, 0, benchTreeSize)
+	seq := []int{41, 38, 31, 12, 19, 8, 9, 32, 6, 100, 2, -1, 57, 23, 21, 0, 0, 1}
+	for _, item := range seq {
+		tree.Insert(IntItem(item))
+	}
 
-	for i := 0; i < benchTreeSize; i++ {
-		vals = append(vals, StringItem(randString(n)))
+	assertEqualItemSlice(t, []Item{IntItem(-1), IntItem(0), IntItem(1)}, tree.KSmallest(3))
+	assertEqualItemSlice(t, []Item{IntItem(100), IntItem(57)}, tree.KLargest(2))
+	assertEqualItemSlice(t, []Item{}, tree.KLargest(-1))
+
+	if n := len(tree.KSmallest(100)); n != tree.Len() {
+		t.Errorf("Expected %d items, got %d", tree.Len(), n)
 	}
 
-	b.StartTimer()
+	subTree, err := tree.SubTree(IntItem(5), IntItem(31))
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
 
-	for i := 0; i < b.N; {
-		b.StopTimer()
+	assertEqualItemSlice(t, []Item{IntItem(6), IntItem(8)}, subTree.KSmallest(2))
+	assertEqualItemSlice(t, []Item{IntItem(31), IntItem(23), IntItem(21)}, subTree.KLargest(3))
+	assertEqualItemSlice(t, []Item{IntItem(9), IntItem(8), IntItem(6)}, subTree.KLargest(100)[5:])
+}
 
-		tree := New()
-		for _, val := range vals {
-			tree.Insert(val)
-		}
+func TestMedian(t *testing.T) {
+	tree := New()
+	if tree.Median() != nil {
+		t.Errorf("Expected nil median for the empty tree")
+	}
 
-		rand.Shuffle(benchTreeSize, func(i, j int) {
-			vals[i], vals[j] = vals[j], vals[i]
-		})
+	expected := make([]int, 0)
+	for _, v := range rand.Perm(200) {
+		tree.Insert(IntItem(v % 150))
+		expected = insertSorted(expected, v%150)
+		assertEqualItems(t, IntItem(expected[(len(expected)+1)/2-1]), tree.Median())
+	}
 
-		b.StartTimer()
-		for _, val := range vals {
-			tree.Find(val)
-			i++
+	for _, v := range rand.Perm(160) {
+		tree.Remove(IntItem(v))
+		expected = removeSorted(expected, v)
 
-			if i >= b.N {
-				break
+		if len(expected) == 0 {
+			if tree.Median() != nil {
+				t.Errorf("Expected nil median, got %v", tree.Median())
 			}
+		} else {
+			assertEqualItems(t, IntItem(expected[(len(expected)+1)/2-1]), tree.Median())
 		}
+
+		assertRBProperties(t, tree)
+	}
+
+	tree.Insert(IntItem(3))
+	tree.Insert(IntItem(1))
+	tree.Insert(IntItem(2))
+	tree.Insert(IntItem(4))
+	tree.Rebuild()
+	assertEqualItems(t, IntItem(2), tree.Median())
+
+	subTree, err := tree.SubTree(IntItem(2), IntItem(4))
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
 	}
+
+	assertEqualItems(t, IntItem(3), subTree.Median())
 }
 
-func BenchmarkExistsInt(b *testing.B) {
-	b.StopTimer()
-	toInsert := perm(benchTreeSize)
-	toFind := perm(benchTreeSize)
-	b.StartTimer()
+func TestClosest(t *testing.T) {
+	tree := New()
+	if tree.Closest(IntItem(1)) != nil {
+		t.Errorf("Expected nil for the empty tree")
+	}
 
-	for i := 0; i < b.N; {
-		b.StopTimer()
+	seq := []int{10, 20, 30, 40}
+	for _, item := range seq {
+		tree.Insert(IntItem(item))
+	}
 
-		tree := New()
-		for _, val := range toInsert {
-			tree.Insert(val)
-		}
+	cases := []struct {
+		item     IntItem
+		expected IntItem
+	}{
+		{-5, 10},
+		{10, 10},
+		{14, 10},
+		{15, 10}, // tie resolves to the smaller one
+		{16, 20},
+		{39, 40},
+		{100, 40},
+	}
 
-		b.StartTimer()
-		for _, val := range toFind {
-			tree.Find(val)
-			i++
+	for _, c := range cases {
+		assertEqualItems(t, c.expected, tree.Closest(c.item))
+	}
 
-			if i >= b.N {
-				break
-			}
-		}
+	strTree := New()
+	strTree.Insert(StringItem("a"))
+	strTree.Insert(StringItem("c"))
+	assertEqualItems(t, StringItem("a"), strTree.Closest(StringItem("b")))
+
+	subTree, err := tree.SubTree(IntItem(15), IntItem(30))
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
 	}
+
+	assertEqualItems(t, IntItem(20), subTree.Closest(IntItem(12)))
+	assertEqualItems(t, IntItem(30), subTree.Closest(IntItem(39)))
+	assertEqualItems(t, IntItem(30), subTree.Closest(IntItem(29)))
 }
 
-func BenchmarkInsert(b *testing.B) {
-	b.StopTimer()
-	vals := perm(benchTreeSize)
-	b.StartTimer()
+func TestFilter(t *testing.T) {
+	tree := NewWithFilter(1000, 0.01)
+	for i := 0; i < 1000; i += 2 {
+		tree.Insert(IntItem(i))
+	}
 
-	for i := 0; i < b.N; {
-		tree := New()
+	for i := 0; i < 1000; i += 4 {
+		tree.Remove(IntItem(i))
+	}
 
-		for _, v := range vals {
-			tree.Insert(v)
-			i++
+	rejected := 0
+	for i := 0; i < 1000; i++ {
+		found := tree.Find(IntItem(i)) != nil
+		if found != (i%4 == 2) {
+			t.Errorf("Unexpected Find result %v for %d", found, i)
+		}
 
-			if i >= b.N {
-				break
-			}
+		if !tree.(*rbTree).filter.mayContain(IntItem(i).Hash()) {
+			rejected++
 		}
 	}
+
+	// 750 absent items, allow a generous false positive rate
+	if rejected < 700 {
+		t.Errorf("Expected the filter to reject most absent items, got %d", rejected)
+	}
+
+	tree.Rebuild()
+	assertEqualItems(t, IntItem(998), tree.Find(IntItem(998)))
 }
 
-func BenchmarkRemove(b *testing.B) {
-	b.StopTimer()
-	toInsert := perm(benchTreeSize)
-	toDelete := perm(benchTreeSize)
-	b.StartTimer()
+func TestOrdered(t *testing.T) {
+	tree := NewOrdered[string]()
+	if _, ok := tree.Min(); ok {
+		t.Errorf("Expected no min value for the empty tree")
+	}
 
-	for i := 0; i < b.N; {
-		b.StopTimer()
+	for _, v := range []string{"pear", "apple", "fig", "apple"} {
+		tree.Insert(v)
+	}
 
-		tree := New()
-		for _, val := range toInsert {
-			tree.Insert(val)
-		}
+	if tree.Len() != 3 {
+		t.Errorf("Expected tree length to be 3, got %d", tree.Len())
+	}
 
-		b.StartTimer()
+	if !tree.Contains("fig") || tree.Contains("plum") {
+		t.Errorf("Unexpected Contains result")
+	}
 
-		for _, val := range toDelete {
-			tree.Remove(val)
-			i++
+	if v, _ := tree.Max(); v != "pear" {
+		t.Errorf("Expected max to be pear, got %s", v)
+	}
 
-			if i >= b.N {
-				break
-			}
+	tree.Remove("apple")
+
+	values := make([]string, 0)
+	tree.Ascend(func(v string) bool {
+		values = append(values, v)
+		return true
+	})
+
+	if len(values) != 2 || values[0] != "fig" || values[1] != "pear" {
+		t.Errorf("Expected [fig pear], got %v", values)
+	}
+}
+
+func TestSetIterators(t *testing.T) {
+	a, b := New(), New()
+	for i := 0; i < 100; i += 2 {
+		a.Insert(IntItem(i))
+	}
+
+	for i := 0; i < 100; i += 3 {
+		b.Insert(IntItem(i))
+	}
+
+	intersection, union := make([]int, 0), make([]int, 0)
+	for i := 0; i < 100; i++ {
+		if i%6 == 0 {
+			intersection = append(intersection, i)
+		}
+
+		if i%2 == 0 || i%3 == 0 {
+			union = append(union, i)
 		}
 	}
 
-}
+	assertEqualIntIterator(t, IntersectIterator(a, b), intersection)
+	assertEqualIntIterator(t, UnionIterator(a, b), union)
+	assertEqualIntIterator(t, IntersectIterator(a, New()), []int{})
+	assertEqualIntIterator(t, UnionIterator(New(), b), []int{0, 3, 6, 9, 12, 15, 18, 21, 24, 27, 30, 33,
+		36, 39, 42, 45, 48, 51, 54, 57, 60, 63, 66, 69, 72, 75, 78, 81, 84, 87, 90, 93, 96, 99})
 
-func BenchmarkRange(b *testing.B) {
-	b.StopTimer()
+	subTree, err := b.SubTree(IntItem(10), IntItem(30))
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
 
-	vals := perm(benchTreeSize)
+	assertEqualIntIterator(t, IntersectIterator(a, subTree), []int{12, 18, 24, 30})
+}
+
+func TestBatch(t *testing.T) {
 	tree := New()
+	for _, item := range []int{1, 2, 3} {
+		tree.Insert(IntItem(item))
+	}
 
-	for _, val := range vals {
-		tree.Insert(val)
+	errAbort := errors.New("abort")
+	err := tree.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(4))
+		tx.Insert(IntItem(2))
+		tx.Remove(IntItem(1))
+		tx.Remove(IntItem(4))
+		tx.Insert(IntItem(5))
+
+		return errAbort
+	})
+
+	if err != errAbort {
+		t.Errorf("Expected error %v, got %v", errAbort, err)
 	}
 
-	sort.Slice(vals, func(i, j int) bool {
-		return vals[i].Less(vals[j])
+	assertEqualIntDataset(t, tree, []int{1, 2, 3})
+
+	err = tree.Batch(func(tx Txn) error {
+		tx.Remove(IntItem(1))
+		tx.Insert(IntItem(7))
+
+		return nil
 	})
 
-	b.StartTimer()
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
 
-	for i := 0; i < b.N; i++ {
-		iter := tree.NewIterator()
+	assertEqualIntDataset(t, tree, []int{2, 3, 7})
 
-		for j := 0; j < benchTreeSize; j++ {
-			val := iter.Next()
+	subTree, _ := tree.SubTree(IntItem(2), IntItem(5))
+	err = subTree.Batch(func(tx Txn) error {
+		tx.Remove(IntItem(3))
+		_, err := tx.Insert(IntItem(9))
 
-			if val != vals[j] {
-				b.Fatalf("expected: %v, got: %v", vals[j], val)
+		return err
+	})
+
+	if err != ErrorOutOfSubTreeRange {
+		t.Errorf("Expected error %v, got %v", ErrorOutOfSubTreeRange, err)
+	}
+
+	assertEqualIntDataset(t, tree, []int{2, 3, 7})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected panic to be propagated")
 			}
+		}()
+
+		tree.Batch(func(tx Txn) error {
+			tx.Remove(IntItem(2))
+			panic("boom")
+		})
+	}()
+
+	assertEqualIntDataset(t, tree, []int{2, 3, 7})
+}
+
+func TestJournal(t *testing.T) {
+	journal := NewJournal(New())
+	journal.Insert(StringItem("b"))
+	journal.Insert(StringItem("a"))
+	journal.Insert(StringItem("b"))
+	journal.Remove(StringItem("a"))
+	journal.Remove(StringItem("z"))
+
+	log := journal.Log()
+	kinds := []OpKind{OpInsert, OpInsert, OpReplace, OpRemove}
+	if len(log) != len(kinds) {
+		t.Fatalf("Expected %d operations, got %d", len(kinds), len(log))
+	}
+
+	for i, op := range log {
+		if op.Kind != kinds[i] {
+			t.Errorf("Expected operation %d kind to be %d, got %d", i, kinds[i], op.Kind)
 		}
 	}
+
+	if ok, err := journal.Undo(); !ok || err != nil || journal.Find(StringItem("a")) == nil {
+		t.Errorf("Expected undo to restore the removed item")
+	}
+
+	journal.Undo()
+	journal.Undo()
+	journal.Undo()
+	if ok, _ := journal.Undo(); ok || journal.Len() != 0 {
+		t.Errorf("Expected all operations to be undone")
+	}
+
+	journal.Redo()
+	journal.Redo()
+	if ok, err := journal.Redo(); !ok || err != nil || journal.Len() != 2 {
+		t.Errorf("Expected redo to reapply operations")
+	}
+
+	journal.Insert(StringItem("c"))
+	if ok, _ := journal.Redo(); ok {
+		t.Errorf("Expected a new operation to discard the undone ones")
+	}
+
+	journal.Batch(func(tx Txn) error {
+		tx.Insert(StringItem("d"))
+		return errors.New("abort")
+	})
+
+	if len(journal.Log()) != 4 || journal.Find(StringItem("d")) != nil {
+		t.Errorf("Expected the rolled back batch to leave no trace")
+	}
+
+	subTree, _ := journal.SubTree(StringItem("a"), StringItem("z"))
+	subTree.Remove(StringItem("c"))
+	journal.Undo()
+
+	if journal.Find(StringItem("c")) == nil {
+		t.Errorf("Expected the sub tree to share the journal")
+	}
+
+	journal.Undo()
+	journal.Batch(func(tx Txn) error {
+		return errors.New("abort")
+	})
+
+	if ok, _ := journal.Redo(); !ok || journal.Find(StringItem("c")) == nil {
+		t.Errorf("Expected the empty aborted batch to keep the redo history")
+	}
+}
+
+func TestJournalErrors(t *testing.T) {
+	errAbort := errors.New("abort")
+	journal := NewJournal(removeFailingTree{New()})
+	journal.Insert(IntItem(1))
+
+	if ok, err := journal.Undo(); ok || err != errRemove {
+		t.Errorf("Expected error %v, got %v", errRemove, err)
+	}
+
+	if len(journal.Log()) != 1 || journal.Find(IntItem(1)) == nil {
+		t.Errorf("Expected the failed undo to keep the operation done")
+	}
+
+	if ok, _ := journal.Redo(); ok {
+		t.Errorf("Expected nothing to redo after the failed undo")
+	}
+
+	err := journal.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(2))
+		return errAbort
+	})
+
+	if !errors.Is(err, errAbort) || !errors.Is(err, errRemove) {
+		t.Errorf("Expected both %v and %v, got %v", errAbort, errRemove, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	tree := New()
+	subTree, _ := tree.SubTree(IntItem(0), IntItem(100))
+	events, cancel := subTree.Watch(IntItem(10), IntItem(1000))
+
+	tree.Insert(IntItem(5))
+	tree.Insert(IntItem(10))
+	tree.Insert(IntItem(10))
+	tree.Insert(IntItem(200))
+	tree.Remove(IntItem(10))
+	tree.Remove(IntItem(5))
+	cancel()
+	cancel()
+	tree.Insert(IntItem(50))
+
+	expected := []ChangeEvent{
+		{Kind: OpInsert, Item: IntItem(10)},
+		{Kind: OpReplace, Item: IntItem(10), Previous: IntItem(10)},
+		{Kind: OpRemove, Item: IntItem(10)},
+	}
+
+	i := 0
+	for event := range events {
+		if i >= len(expected) || event != expected[i] {
+			t.Errorf("Unexpected event %v at %d", event, i)
+		}
+
+		i++
+	}
+
+	if i != len(expected) {
+		t.Errorf("Expected %d events, got %d", len(expected), i)
+	}
+}
+
+func TestWatchOverflow(t *testing.T) {
+	tree := New()
+	events, cancel := tree.Watch(IntItem(0), IntItem(1000))
+
+	for i := 0; i < 2*watchBufferSize; i++ {
+		tree.Insert(IntItem(i))
+	}
+
+	n := 0
+	for range events {
+		n++
+	}
+
+	if n != watchBufferSize {
+		t.Errorf("Expected %d buffered events, got %d", watchBufferSize, n)
+	}
+
+	cancel()
+	tree.Insert(IntItem(1))
+}
+
+func TestWatchCancelFromConsumer(t *testing.T) {
+	tree := New()
+	events, cancel := tree.Watch(IntItem(0), IntItem(1000))
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 1000; i++ {
+			tree.Insert(IntItem(i % 100))
+		}
+	}()
+
+	<-events
+	cancel()
+	<-done
+
+	for range events {
+	}
+}
+
+func TestWatchBounds(t *testing.T) {
+	tree := New()
+	events, cancel := tree.Watch(IntItem(0), IntItem(10))
+
+	tree.Insert(IntItem(-1))
+	tree.Insert(IntItem(0))
+	tree.Insert(IntItem(9))
+	tree.Insert(IntItem(10))
+	cancel()
+
+	expected := []Item{IntItem(0), IntItem(9)}
+	actual := make([]Item, 0)
+	for event := range events {
+		actual = append(actual, event.Item)
+	}
+
+	assertEqualItemSlice(t, expected, actual)
+}
+
+func TestWatchBatch(t *testing.T) {
+	tree := New()
+	journal := NewJournal(tree)
+	events, cancel := tree.Watch(IntItem(0), IntItem(10))
+
+	tree.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(1))
+		return errors.New("abort")
+	})
+
+	journal.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(2))
+		return errors.New("abort")
+	})
+
+	tree.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(3))
+		if len(events) != 0 {
+			t.Errorf("Expected events to be held until commit")
+		}
+
+		return nil
+	})
+
+	NewWAL(tree, &bytes.Buffer{}, intCodec{}).Batch(func(tx Txn) error {
+		tx.Insert(IntItem(4))
+		return nil
+	})
+
+	cancel()
+
+	expected := []Item{IntItem(3), IntItem(4)}
+	actual := make([]Item, 0)
+	for event := range events {
+		actual = append(actual, event.Item)
+	}
+
+	assertEqualItemSlice(t, expected, actual)
+}
+
+// flakyItem compares by key unless broken is set, then the order is reversed.
+type flakyItem struct {
+	key    int
+	broken *bool
+}
+
+func (el flakyItem) Less(other Item) bool {
+	if *el.broken {
+		return el.key > other.(flakyItem).key
+	}
+
+	return el.key < other.(flakyItem).key
+}
+
+func TestRepair(t *testing.T) {
+	broken := false
+	tree := New()
+	if tree.Repair() {
+		t.Errorf("Expected the empty tree not to be repaired")
+	}
+
+	broken = true
+	for _, key := range []int{1, 2, 3} {
+		tree.Insert(flakyItem{key, &broken})
+	}
+
+	broken = false
+	for _, key := range []int{4, 5, 6} {
+		tree.Insert(flakyItem{key, &broken})
+	}
+
+	if !tree.Repair() {
+		t.Errorf("Expected the tree to be repaired")
+	}
+
+	assertRBProperties(t, tree)
+	for key := 1; key <= 6; key++ {
+		if tree.Find(flakyItem{key, &broken}) == nil {
+			t.Errorf("Expected to find %d after repair", key)
+		}
+	}
+
+	if tree.Repair() {
+		t.Errorf("Expected the consistent tree not to be repaired")
+	}
+
+	assertPanicsWith := func(fn func()) {
+		defer func() {
+			if r := recover(); r != ErrorInconsistentOrdering {
+				t.Errorf("Expected panic %v, got %v", ErrorInconsistentOrdering, r)
+			}
+		}()
+
+		fn()
+	}
+
+	assertPanicsWith(func() { checkAsymmetry(brokenItem(1), brokenItem(2)) })
+
+	fold := false
+	tree = New()
+	for _, s := range []string{"a", "A", "b"} {
+		tree.Insert(foldedItem{s, &fold})
+	}
+
+	events, cancel := tree.Watch(foldedItem{"a", &fold}, foldedItem{"c", &fold})
+	fold = true
+	if !tree.Repair() || tree.Len() != 2 {
+		t.Errorf("Expected equal items to be collapsed")
+	}
+
+	cancel()
+	expected := []ChangeEvent{{Kind: OpReplace, Item: foldedItem{"a", &fold}, Previous: foldedItem{"A", &fold}}}
+	i := 0
+	for event := range events {
+		if i >= len(expected) || event != expected[i] {
+			t.Errorf("Unexpected event %v at %d", event, i)
+		}
+
+		i++
+	}
+
+	if i != len(expected) {
+		t.Errorf("Expected %d events, got %d", len(expected), i)
+	}
+
+	tree = New()
+	broken = false
+	tree.Insert(flakyItem{1, &broken})
+	tree.Insert(flakyItem{3, &broken})
+	z, _ := tree.(*rbTree).find(flakyItem{3, &broken})
+	broken = true
+	assertPanicsWith(func() { checkNeighbours(z) })
+}
+
+// foldedItem is a string which is compared case-insensitively once fold is set.
+type foldedItem struct {
+	s    string
+	fold *bool
+}
+
+func (el foldedItem) Less(other Item) bool {
+	if *el.fold {
+		return strings.ToLower(el.s) < strings.ToLower(other.(foldedItem).s)
+	}
+
+	return el.s < other.(foldedItem).s
+}
+
+// brokenItem claims to be less than any other item.
+type brokenItem int
+
+func (el brokenItem) Less(other Item) bool {
+	return true
+}
+
+func TestPrefixRange(t *testing.T) {
+	tree := New()
+	for _, s := range []string{"a", "ab", "abc", "abd", "ac", "b", "\xff", "\xff\xff", "\xffa"} {
+		tree.Insert(StringItem(s))
+	}
+
+	key := func(s string) Item { return StringItem(s) }
+	cases := []struct {
+		prefix   string
+		expected []Item
+	}{
+		{"ab", []Item{StringItem("ab"), StringItem("abc"), StringItem("abd")}},
+		{"ac", []Item{StringItem("ac")}},
+		{"x", []Item{}},
+		{"\xff", []Item{StringItem("\xff"), StringItem("\xffa"), StringItem("\xff\xff")}},
+		{"\xff\xff", []Item{StringItem("\xff\xff")}},
+	}
+
+	for _, c := range cases {
+		view, err := PrefixRange(tree, c.prefix, key)
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+
+		assertEqualItemSlice(t, c.expected, view.KSmallest(100))
+		assertEqualItemSlice(t, c.expected, reverse(view.KLargest(100)))
+
+		if view.Len() != len(c.expected) {
+			t.Errorf("Expected length %d for prefix %q, got %d", len(c.expected), c.prefix, view.Len())
+		}
+	}
+
+	view, _ := PrefixRange(tree, "a", key)
+	if _, err := view.Insert(StringItem("b")); err != ErrorOutOfSubTreeRange {
+		t.Errorf("Expected error %v, got %v", ErrorOutOfSubTreeRange, err)
+	}
+
+	assertEqualItems(t, StringItem("ac"), view.Max())
+
+	subTree, _ := tree.SubTree(StringItem("abc"), StringItem("b"))
+	view, _ = PrefixRange(subTree, "ab", key)
+	assertEqualItemSlice(t, []Item{StringItem("abc"), StringItem("abd")}, view.KSmallest(100))
+
+	if _, err := PrefixRange(NewJournal(tree), "ab", key); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	sparse := New()
+	sparse.Insert(StringItem("a"))
+	sparse.Insert(StringItem("c"))
+
+	view, _ = PrefixRange(sparse, "b", key)
+	if view.Min() != nil || view.Max() != nil {
+		t.Errorf("Expected nil bounds for an empty prefix range, got %v and %v", view.Min(), view.Max())
+	}
+
+	if view.Len() != 0 {
+		t.Errorf("Expected length 0, got %d", view.Len())
+	}
+}
+
+func reverse(items []Item) []Item {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+
+	return items
+}
+
+func TestCollation(t *testing.T) {
+	collation := NewCollation(func(s string) []byte {
+		return []byte(strings.ToLower(s))
+	})
+
+	tree := NewWithFilter(10, 0.01)
+	for _, s := range []string{"banana", "Apple", "cherry", "apple"} {
+		tree.Insert(collation.Item(s))
+	}
+
+	names := make([]string, 0)
+	iter := tree.NewIterator()
+	for item := iter.Next(); item != nil; item = iter.Next() {
+		names = append(names, item.(CollatedString).String())
+	}
+
+	if strings.Join(names, ",") != "apple,banana,cherry" {
+		t.Errorf("Expected apple,banana,cherry, got %v", names)
+	}
+
+	if tree.Find(collation.Item("BANANA")) == nil {
+		t.Errorf("Expected to find BANANA")
+	}
+
+	if tree.Find(collation.Item("date")) != nil {
+		t.Errorf("Expected not to find date")
+	}
+}
+
+type intCodec struct{}
+
+func (intCodec) Encode(item Item) ([]byte, error) {
+	return []byte(strconv.Itoa(int(item.(IntItem)))), nil
+}
+
+func (intCodec) Decode(data []byte) (Item, error) {
+	v, err := strconv.Atoi(string(data))
+	return IntItem(v), err
+}
+
+// failingWriter fails all writes once broken is set.
+type failingWriter struct {
+	bytes.Buffer
+	broken bool
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.broken {
+		return 0, errDiskFull
+	}
+
+	return w.Buffer.Write(p)
+}
+
+func TestWALBatch(t *testing.T) {
+	var log failingWriter
+
+	tree := New()
+	wal := NewWAL(tree, &log, intCodec{})
+	err := wal.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(1))
+		tx.Insert(IntItem(2))
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	size := log.Len()
+	log.broken = true
+	err = wal.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(3))
+		tx.Remove(IntItem(1))
+		return nil
+	})
+
+	if !errors.Is(err, errDiskFull) {
+		t.Errorf("Expected error %v, got %v", errDiskFull, err)
+	}
+
+	assertEqualIntDataset(t, tree, []int{1, 2})
+
+	log.broken = false
+	wal.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(4))
+		return errors.New("abort")
+	})
+
+	if log.Len() != size {
+		t.Errorf("Expected failed batches to log nothing")
+	}
+
+	restored := New()
+	if err := Recover(restored, bytes.NewReader(log.Bytes()), intCodec{}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	assertEqualIntDataset(t, restored, []int{1, 2})
+
+	if _, err := wal.Insert(IntItem(5)); err != ErrorLogFailed {
+		t.Errorf("Expected error %v, got %v", ErrorLogFailed, err)
+	}
+}
+
+// cutWriter accepts up to limit bytes and fails afterwards.
+type cutWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *cutWriter) Write(p []byte) (int, error) {
+	if n := w.limit - w.Len(); len(p) > n {
+		w.Buffer.Write(p[:n])
+		return n, errDiskFull
+	}
+
+	return w.Buffer.Write(p)
+}
+
+func TestWALBatchCut(t *testing.T) {
+	log := &cutWriter{limit: 14}
+	tree := New()
+	wal := NewWAL(tree, log, intCodec{})
+
+	err := wal.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(10))
+		tx.Insert(IntItem(20))
+		tx.Insert(IntItem(30))
+		return nil
+	})
+
+	if err != errDiskFull || tree.Len() != 0 || log.Len() != log.limit {
+		t.Errorf("Expected the batch to be cut short, got %v", err)
+	}
+
+	log.limit = 1000
+	if _, err := wal.Insert(IntItem(40)); err != ErrorLogFailed {
+		t.Errorf("Expected error %v, got %v", ErrorLogFailed, err)
+	}
+
+	restored := New()
+	if err := Recover(restored, bytes.NewReader(log.Bytes()), intCodec{}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	assertEqualIntDataset(t, restored, []int{})
+
+	var full bytes.Buffer
+	wal = NewWAL(New(), &full, intCodec{})
+	wal.Insert(IntItem(1))
+	wal.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(2))
+		tx.Remove(IntItem(1))
+		return nil
+	})
+
+	record := full.Bytes()
+	for size := len(record) - 1; size >= 0; size-- {
+		restored := New()
+		if err := Recover(restored, bytes.NewReader(record[:size]), intCodec{}); err != nil {
+			t.Errorf("Unexpected error %v for %d bytes", err, size)
+		}
+
+		if restored.Find(IntItem(2)) != nil {
+			t.Errorf("Expected the cut batch to be dropped at %d bytes", size)
+		}
+	}
+
+	restored = New()
+	if err := Recover(restored, bytes.NewReader(record), intCodec{}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	assertEqualIntDataset(t, restored, []int{2})
+}
+
+// removeFailingTree rejects all removals.
+type removeFailingTree struct {
+	Tree
+}
+
+var errRemove = errors.New("remove failed")
+
+func (rt removeFailingTree) Remove(item Item) (bool, error) {
+	return false, errRemove
+}
+
+func TestBatchRollbackError(t *testing.T) {
+	errAbort := errors.New("abort")
+	tree := removeFailingTree{New()}
+
+	err := batch(tree, func(tx Txn) error {
+		tx.Insert(IntItem(1))
+		return errAbort
+	})
+
+	if !errors.Is(err, errAbort) || !errors.Is(err, errRemove) {
+		t.Errorf("Expected both %v and %v, got %v", errAbort, errRemove, err)
+	}
+}
+
+// largeCodec pads encoded items to get multi-byte record lengths.
+type largeCodec struct{}
+
+func (largeCodec) Encode(item Item) ([]byte, error) {
+	return []byte(fmt.Sprintf("%200d", int(item.(IntItem)))), nil
+}
+
+func (largeCodec) Decode(data []byte) (Item, error) {
+	v, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return IntItem(v), err
+}
+
+// brokenReader returns the data and then fails.
+type brokenReader struct {
+	data []byte
+}
+
+var errRead = errors.New("read failed")
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errRead
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// hugeCodec encodes items beyond the record size limit.
+type hugeCodec struct {
+	intCodec
+}
+
+func (hugeCodec) Encode(item Item) ([]byte, error) {
+	return make([]byte, maxRecordSize+1), nil
+}
+
+func TestWALValidation(t *testing.T) {
+	var log bytes.Buffer
+
+	journal := NewJournal(New())
+	view, _ := journal.SubTree(IntItem(0), IntItem(10))
+	wal := NewWAL(view, &log, intCodec{})
+
+	if _, err := wal.Insert(IntItem(20)); err != ErrorOutOfSubTreeRange {
+		t.Errorf("Expected error %v, got %v", ErrorOutOfSubTreeRange, err)
+	}
+
+	if _, err := wal.Remove(IntItem(20)); err != ErrorOutOfSubTreeRange {
+		t.Errorf("Expected error %v, got %v", ErrorOutOfSubTreeRange, err)
+	}
+
+	if log.Len() != 0 {
+		t.Errorf("Expected rejected mutations not to be logged")
+	}
+
+	wal = NewWAL(New(), &log, hugeCodec{})
+	if _, err := wal.Insert(IntItem(1)); err != ErrorRecordTooLarge {
+		t.Errorf("Expected error %v, got %v", ErrorRecordTooLarge, err)
+	}
+}
+
+func TestWALTornTail(t *testing.T) {
+	var log bytes.Buffer
+
+	wal := NewWAL(New(), &log, largeCodec{})
+	wal.Insert(IntItem(1))
+	size := log.Len()
+	wal.Insert(IntItem(2))
+
+	// cut inside the two-byte length, then inside the payload
+	for _, cut := range []int{size + 2, size + 50} {
+		restored := New()
+		if err := Recover(restored, bytes.NewReader(log.Bytes()[:cut]), largeCodec{}); err != nil {
+			t.Errorf("Unexpected error %v for cut at %d", err, cut)
+		}
+
+		assertEqualIntDataset(t, restored, []int{1})
+	}
+
+	for _, cut := range []int{size + 2, size + 50} {
+		r := &brokenReader{append([]byte(nil), log.Bytes()[:cut]...)}
+		if err := Recover(New(), r, largeCodec{}); err != errRead {
+			t.Errorf("Expected error %v for cut at %d, got %v", errRead, cut, err)
+		}
+	}
+}
+
+func TestWAL(t *testing.T) {
+	var log bytes.Buffer
+
+	checkpoint := []int{1, 2}
+	tree := New()
+	for _, v := range checkpoint {
+		tree.Insert(IntItem(v))
+	}
+
+	wal := NewWAL(tree, &log, intCodec{})
+	wal.Insert(IntItem(10))
+	wal.Remove(IntItem(1))
+	wal.Remove(IntItem(7))
+	wal.Batch(func(tx Txn) error {
+		tx.Insert(IntItem(20))
+		return errors.New("abort")
+	})
+
+	subTree, _ := wal.SubTree(IntItem(0), IntItem(5))
+	if _, err := subTree.Insert(IntItem(30)); err != ErrorOutOfSubTreeRange {
+		t.Errorf("Expected error %v, got %v", ErrorOutOfSubTreeRange, err)
+	}
+
+	subTree.Insert(IntItem(3))
+
+	restore := func(data []byte) (Tree, error) {
+		restored := New()
+		for _, v := range checkpoint {
+			restored.Insert(IntItem(v))
+		}
+
+		return restored, Recover(restored, bytes.NewReader(data), intCodec{})
+	}
+
+	restored, err := restore(log.Bytes())
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	assertEqualIntDataset(t, tree, []int{2, 3, 10})
+	assertEqualIntDataset(t, restored, []int{2, 3, 10})
+
+	// a torn last record is ignored
+	restored, err = restore(log.Bytes()[:log.Len()-2])
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	assertEqualIntDataset(t, restored, []int{2, 10})
+
+	corrupted := append([]byte(nil), log.Bytes()...)
+	corrupted[2] ^= 0xff
+	if _, err = restore(corrupted); err != ErrorCorruptedLog {
+		t.Errorf("Expected error %v, got %v", ErrorCorruptedLog, err)
+	}
+}
+
+func TestStructure(t *testing.T) {
+	if Structure(New()) != nil {
+		t.Errorf("Expected nil structure for the empty tree")
+	}
+
+	tree := New()
+	for _, v := range []int{1, 2, 3} {
+		tree.Insert(IntItem(v))
+	}
+
+	root := Structure(NewJournal(tree))
+	if root == nil || root.Item != IntItem(2) || root.Red || !root.Left.Red || !root.Right.Red {
+		t.Errorf("Unexpected structure %+v", root)
+	}
+
+	if root.Left.Left != nil || root.Right.Right != nil {
+		t.Errorf("Expected leaves to have no children")
+	}
+}
+
+func TestMultiIndex(t *testing.T) {
+	type player struct {
+		id    int
+		score int
+	}
+
+	const byID, byScore = 0, 1
+	players := NewMultiIndex(
+		func(a, b player) bool { return a.id < b.id },
+		func(a, b player) bool { return a.score < b.score },
+	)
+
+	players.Insert(player{1, 20})
+	players.Insert(player{2, 20})
+	players.Insert(player{3, 10})
+
+	if players.Len() != 3 {
+		t.Errorf("Expected equal scores to be kept, got length %d", players.Len())
+	}
+
+	// replaces player 1 by the primary key
+	if players.Insert(player{1, 30}) {
+		t.Errorf("Expected insert to replace player 1")
+	}
+
+	if players.Len() != 3 {
+		t.Errorf("Expected length to be 3, got %d", players.Len())
+	}
+
+	if p, _ := players.Find(byID, player{id: 1}); p.score != 30 {
+		t.Errorf("Expected player 1 to have score 30, got %v", p)
+	}
+
+	players.Insert(player{4, 20})
+	if p, _ := players.Find(byScore, player{score: 20}); p.id != 2 {
+		t.Errorf("Expected player 2 to be the first with score 20, got %v", p)
+	}
+
+	if !players.Remove(byScore, player{score: 20}) {
+		t.Errorf("Expected a player with score 20 to be removed")
+	}
+
+	if _, ok := players.Find(byID, player{id: 2}); ok {
+		t.Errorf("Expected player 2 to be removed from all indexes")
+	}
+
+	if players.Remove(byScore, player{score: 50}) {
+		t.Errorf("Expected no player with score 50")
+	}
+
+	ids := make([]int, 0)
+	players.Ascend(byScore, func(p player) bool {
+		ids = append(ids, p.id)
+		return true
+	})
+
+	if len(ids) != 3 || ids[0] != 3 || ids[1] != 4 || ids[2] != 1 {
+		t.Errorf("Expected [3 4 1], got %v", ids)
+	}
+}
+
+var letters = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+
+func randString(n int) string {
+	b := make([]byte, n)
+	m := len(letters)
+
+	for i := range b {
+		b[i] = letters[rand.Intn(m)]
+	}
+
+	return string(b)
+}
+
+func assertEqualItems(t *testing.T, a, b Item) {
+	if (a != b) || a.Less(b) || b.Less(a) {
+		t.Errorf("Expected %d, got %d", a, b)
+	}
+}
+
+func assertRBProperties(t *testing.T, tree Tree) {
+	rb := tree.(*rbTree)
+	if rb.root.color != black {
+		t.Errorf("Expected root to be black")
+	}
+
+	var blackHeight func(n *node) int
+	blackHeight = func(n *node) int {
+		if n == tNil {
+			return 1
+		}
+
+		if n.left != tNil && n.left.parent != n || n.right != tNil && n.right.parent != n {
+			t.Errorf("Broken parent link at %v", n.item)
+		}
+
+		if n.color == red && (n.left.color == red || n.right.color == red) {
+			t.Errorf("Expected children of red node %v to be black", n.item)
+		}
+
+		l, r := blackHeight(n.left), blackHeight(n.right)
+		if l != r {
+			t.Errorf("Expected equal black heights at %v, got %d and %d", n.item, l, r)
+		}
+
+		if n.color == black {
+			l++
+		}
+
+		return l
+	}
+
+	blackHeight(rb.root)
+}
+
+func height(n *node) int {
+	if n == tNil {
+		return 0
+	}
+
+	l, r := height(n.left), height(n.right)
+	if l < r {
+		l = r
+	}
+
+	return l + 1
+}
+
+func assertEqualItemSlice(t *testing.T, expected, actual []Item) {
+	if len(expected) != len(actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+		return
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Errorf("Expected %v, got %v", expected, actual)
+			return
+		}
+	}
+}
+
+func assertEqualIntDataset(t *testing.T, tree Tree, dataset []int) {
+	assertEqualIntIterator(t, tree.NewIterator(), dataset)
+}
+
+func assertEqualIntIterator(t *testing.T, iter Iterator, dataset []int) {
+	i := 0
+
+	for {
+		val := iter.Next()
+		if val == nil {
+			break
+		}
+
+		if IntItem(dataset[i]) != val {
+			t.Errorf("Expected at {%d} to be %d, got %d", i, dataset[i], val)
+		}
+
+		i++
+	}
+
+	if i != len(dataset) {
+		t.Errorf("Expected to iterate {%d}, got %d", len(dataset), i)
+	}
+}
+
+func BenchmarkExistsString(b *testing.B) {
+	b.StopTimer()
+
+	n := 10
+	vals := make([]StringItem, 0, benchTreeSize)
+
+	for i := 0; i < benchTreeSize; i++ {
+		vals = append(vals, StringItem(randString(n)))
+	}
+
+	b.StartTimer()
+
+	for i := 0; i < b.N; {
+		b.StopTimer()
+
+		tree := New()
+		for _, val := range vals {
+			tree.Insert(val)
+		}
+
+		rand.Shuffle(benchTreeSize, func(i, j int) {
+			vals[i], vals[j] = vals[j], vals[i]
+		})
+
+		b.StartTimer()
+		for _, val := range vals {
+			tree.Find(val)
+			i++
+
+			if i >= b.N {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkExistsInt(b *testing.B) {
+	b.StopTimer()
+	toInsert := perm(benchTreeSize)
+	toFind := perm(benchTreeSize)
+	b.StartTimer()
+
+	for i := 0; i < b.N; {
+		b.StopTimer()
+
+		tree := New()
+		for _, val := range toInsert {
+			tree.Insert(val)
+		}
+
+		b.StartTimer()
+		for _, val := range toFind {
+			tree.Find(val)
+			i++
+
+			if i >= b.N {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkInsert(b *testing.B) {
+	b.StopTimer()
+	vals := perm(benchTreeSize)
+	b.StartTimer()
+
+	for i := 0; i < b.N; {
+		tree := New()
+
+		for _, v := range vals {
+			tree.Insert(v)
+			i++
+
+			if i >= b.N {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkRemove(b *testing.B) {
+	b.StopTimer()
+	toInsert := perm(benchTreeSize)
+	toDelete := perm(benchTreeSize)
+	b.StartTimer()
+
+	for i := 0; i < b.N; {
+		b.StopTimer()
+
+		tree := New()
+		for _, val := range toInsert {
+			tree.Insert(val)
+		}
+
+		b.StartTimer()
+
+		for _, val := range toDelete {
+			tree.Remove(val)
+			i++
+
+			if i >= b.N {
+				break
+			}
+		}
+	}
+
+}
+
+func BenchmarkRange(b *testing.B) {
+	b.StopTimer()
+
+	vals := perm(benchTreeSize)
+	tree := New()
+
+	for _, val := range vals {
+		tree.Insert(val)
+	}
+
+	sort.Slice(vals, func(i, j int) bool {
+		return vals[i].Less(vals[j])
+	})
+
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		iter := tree.NewIterator()
+
+		for j := 0; j < benchTreeSize; j++ {
+			val := iter.Next()
+
+			if val != vals[j] {
+				b.Fatalf("expected: %v, got: %v", vals[j], val)
+			}
+		}
+	}
+}
+
+func insertSorted(dataset []int, v int) []int {
+	i := sort.SearchInts(dataset, v)
+	if i < len(dataset) && dataset[i] == v {
+		return dataset
+	}
+
+	dataset = append(dataset, 0)
+	copy(dataset[i+1:], dataset[i:])
+	dataset[i] = v
+
+	return dataset
+}
+
+func removeSorted(dataset []int, v int) []int {
+	i := sort.SearchInts(dataset, v)
+	if i == len(dataset) || dataset[i] != v {
+		return dataset
+	}
+
+	return append(dataset[:i], dataset[i+1:]...)
 }
 
 func perm(size int) []IntItem {
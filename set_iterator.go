@@ -0,0 +1,110 @@
+package rbtree
+
+// seeker is implemented by iterators that can skip forward in O(log n).
+type seeker interface {
+	seek(item Item) Item
+}
+
+// intersectIterator yields items present in both trees.
+type intersectIterator struct {
+	a, b    Iterator
+	current Item
+}
+
+// unionIterator yields items present in either of the trees.
+type unionIterator struct {
+	a, b    Iterator
+	x, y    Item
+	started bool
+	current Item
+}
+
+// IntersectIterator returns an iterator over items present in both trees in ascending order.
+// Items are taken from a. The iterator skips non-matching runs using tree lookups
+// and does not materialize the result.
+func IntersectIterator(a, b Tree) Iterator {
+	return &intersectIterator{a: a.NewIterator(), b: b.NewIterator()}
+}
+
+// UnionIterator returns an iterator over items present in either of the trees in ascending order.
+// Items present in both trees are taken from a. The result is not materialized.
+func UnionIterator(a, b Tree) Iterator {
+	return &unionIterator{a: a.NewIterator(), b: b.NewIterator()}
+}
+
+// IsValid returns true if the iterator is valid, otherwise returns false.
+func (it *intersectIterator) IsValid() bool {
+	return it.current != nil
+}
+
+// Next moves the iterator to the next element and returns it.
+func (it *intersectIterator) Next() Item {
+	x, y := it.a.Next(), it.b.Next()
+
+	for x != nil && y != nil {
+		if x.Less(y) {
+			x = advance(it.a, y)
+		} else if y.Less(x) {
+			y = advance(it.b, x)
+		} else {
+			break
+		}
+	}
+
+	if x == nil || y == nil {
+		x = nil
+	}
+
+	it.current = x
+	return x
+}
+
+// Get returns the current pointed element. Return nil if the iterator is invalid.
+func (it *intersectIterator) Get() Item {
+	return it.current
+}
+
+// IsValid returns true if the iterator is valid, otherwise returns false.
+func (it *unionIterator) IsValid() bool {
+	return it.current != nil
+}
+
+// Next moves the iterator to the next element and returns it.
+func (it *unionIterator) Next() Item {
+	if !it.started {
+		it.x, it.y = it.a.Next(), it.b.Next()
+		it.started = true
+	}
+
+	switch {
+	case it.x == nil && it.y == nil:
+		it.current = nil
+	case it.y == nil || (it.x != nil && it.x.Less(it.y)):
+		it.current, it.x = it.x, it.a.Next()
+	case it.x == nil || it.y.Less(it.x):
+		it.current, it.y = it.y, it.b.Next()
+	default:
+		it.current, it.x, it.y = it.x, it.a.Next(), it.b.Next()
+	}
+
+	return it.current
+}
+
+// Get returns the current pointed element. Return nil if the iterator is invalid.
+func (it *unionIterator) Get() Item {
+	return it.current
+}
+
+// advance moves the given iterator to the least element greater than or equal to the given item.
+func advance(it Iterator, item Item) Item {
+	if s, ok := it.(seeker); ok {
+		return s.seek(item)
+	}
+
+	x := it.Next()
+	for x != nil && x.Less(item) {
+		x = it.Next()
+	}
+
+	return x
+}
@@ -0,0 +1,40 @@
+package rbtree
+
+// NodeInfo describes a node of the tree structure, see Structure.
+type NodeInfo struct {
+	Item        Item
+	Red         bool
+	Left, Right *NodeInfo
+}
+
+// Structure returns a snapshot of the internal structure of the given tree, which is
+// useful for visualization and debugging. Views and wrappers are described by their
+// backing tree. Returns nil if the tree is empty or its implementation is not supported.
+func Structure(tree Tree) *NodeInfo {
+	switch t := tree.(type) {
+	case *rbTree:
+		return describe(t.root)
+	case *subTree:
+		return describe(t.tree.root)
+	case *Journal:
+		return Structure(t.Tree)
+	case *WAL:
+		return Structure(t.Tree)
+	}
+
+	return nil
+}
+
+// describe returns a snapshot of the subtree rooted at the given node.
+func describe(x *node) *NodeInfo {
+	if x == tNil {
+		return nil
+	}
+
+	return &NodeInfo{
+		Item:  x.item,
+		Red:   x.color == red,
+		Left:  describe(x.left),
+		Right: describe(x.right),
+	}
+}
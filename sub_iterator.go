@@ -3,14 +3,14 @@ package rbtree
 // subIterator implements Iterator interface for the sub tree collection.
 type subIterator struct {
 	iterator *iterator
-	toKey    Item
+	view     *subTree
 }
 
 // IsValid returns true if the iterator is valid, otherwise returns false.
 func (it *subIterator) IsValid() bool {
 	node := it.iterator.node
 
-	return it.iterator.IsValid() && node != nil && node.item != nil && !it.toKey.Less(node.item)
+	return it.iterator.IsValid() && node != nil && node.item != nil && it.view.belowTo(node.item)
 }
 
 // Next moves the iterator to the next element and returns it.
@@ -21,7 +21,7 @@ func (it *subIterator) Next() Item {
 
 	item := it.iterator.Next()
 
-	if item != nil && it.toKey.Less(item) {
+	if item != nil && !it.view.belowTo(item) {
 		it.iterator.state = pastRear
 		return nil
 	}
@@ -37,3 +37,16 @@ func (it *subIterator) Get() Item {
 
 	return it.iterator.node.item
 }
+
+// seek moves the iterator to the least element greater than or equal to
+// the given item and returns it. The item must not be less than the current element.
+func (it *subIterator) seek(item Item) Item {
+	found := it.iterator.seek(item)
+
+	if found != nil && !it.view.belowTo(found) {
+		it.iterator.state = pastRear
+		return nil
+	}
+
+	return found
+}
@@ -10,7 +10,9 @@ var ErrorOutOfSubTreeRange error = errors.New("Given key is out of sub tree rang
 type subTree struct {
 	tree    *rbTree
 	fromKey Item
-	toKey   Item
+	toKey   Item // nil means there is no upper bound
+	// exclusive tells whether toKey itself is out of the view.
+	exclusive bool
 }
 
 // Returns the number of items in the tree.
@@ -58,8 +60,13 @@ func (st *subTree) Find(item Item) Item {
 
 // Returns the min element in the sub tree
 func (st *subTree) Min() Item {
+	if debug {
+		st.tree.beginRead()
+		defer st.tree.endRead()
+	}
+
 	node := st.tree.root.ceiling(st.fromKey)
-	if node == tNil {
+	if node == tNil || !st.inRange(node.item) {
 		return nil
 	}
 
@@ -68,23 +75,94 @@ func (st *subTree) Min() Item {
 
 // Returns the max element in the sub tree
 func (st *subTree) Max() Item {
-	node := st.tree.root.floor(st.toKey)
-	if node == tNil {
+	if debug {
+		st.tree.beginRead()
+		defer st.tree.endRead()
+	}
+
+	node := st.last()
+	if node == tNil || !st.inRange(node.item) {
 		return nil
 	}
 
 	return node.item
 }
 
+// Closest returns the item of the sub tree nearest to the given one, see Tree.Closest.
+func (st *subTree) Closest(item Item) Item {
+	if debug {
+		st.tree.beginRead()
+		defer st.tree.endRead()
+	}
+
+	root := st.tree.root
+	floor, ceiling := tNil, tNil
+
+	if item.Less(st.fromKey) {
+		ceiling = root.ceiling(st.fromKey)
+	} else if !st.belowTo(item) {
+		floor = st.last()
+	} else {
+		floor, ceiling = root.floor(item), root.ceiling(item)
+	}
+
+	if floor != tNil && !st.inRange(floor.item) {
+		floor = tNil
+	}
+
+	if ceiling != tNil && !st.inRange(ceiling.item) {
+		ceiling = tNil
+	}
+
+	return closest(item, floor, ceiling)
+}
+
+// Median returns the ⌈n/2⌉-th smallest item of the sub tree in O(n), or nil if the sub tree is empty.
+func (st *subTree) Median() Item {
+	n := st.Len()
+	if n == 0 {
+		return nil
+	}
+
+	return st.KSmallest((n + 1) / 2)[(n+1)/2-1]
+}
+
+// KSmallest returns up to k smallest items of the sub tree in ascending order.
+func (st *subTree) KSmallest(k int) []Item {
+	if debug {
+		st.tree.beginRead()
+		defer st.tree.endRead()
+	}
+
+	return collect(st.tree.root.ceiling(st.fromKey), st.tree.clamp(k), (*node).next, st.inRange)
+}
+
+// KLargest returns up to k largest items of the sub tree in descending order.
+func (st *subTree) KLargest(k int) []Item {
+	if debug {
+		st.tree.beginRead()
+		defer st.tree.endRead()
+	}
+
+	return collect(st.last(), st.tree.clamp(k), (*node).prev, st.inRange)
+}
+
 // SubTree returns a view of the portion of this tree whose keys range from
 // fromKey, inclusive, to toKey, exclusive.
 func (st *subTree) NewIterator() Iterator {
+	if debug {
+		st.tree.beginRead()
+		defer st.tree.endRead()
+	}
+
 	return &subIterator{
 		iterator: &iterator{
-			node:  st.tree.root.ceiling(st.fromKey),
-			state: beforeFirst,
+			node:     st.tree.root.ceiling(st.fromKey),
+			state:    beforeFirst,
+			tree:     st.tree,
+			rebuilds: st.tree.rebuilds,
 		},
-		toKey: st.toKey,
+		view: st,
 	}
 }
 
@@ -98,7 +176,61 @@ func (st *subTree) SubTree(fromKey, toKey Item) (Tree, error) {
 	return st.tree.SubTree(fromKey, toKey)
 }
 
+// Batch calls fn with a transaction over the sub tree, see Tree.Batch.
+func (st *subTree) Batch(fn func(tx Txn) error) error {
+	return batch(st, fn)
+}
+
+// Watch subscribes to changes within the intersection of the given range and the sub tree range, see Tree.Watch.
+func (st *subTree) Watch(fromKey, toKey Item) (<-chan ChangeEvent, func()) {
+	return st.tree.watch(func(item Item) bool {
+		return !item.Less(fromKey) && item.Less(toKey) && st.inRange(item)
+	})
+}
+
+// Rebuild reconstructs the whole underlying tree into an optimally balanced shape.
+func (st *subTree) Rebuild() {
+	st.tree.Rebuild()
+}
+
+// Repair re-sorts and rebuilds the whole underlying tree if its items are found out of order.
+func (st *subTree) Repair() bool {
+	return st.tree.Repair()
+}
+
 // Returns true if the given item in the subTree range, otherwise return false
 func (st *subTree) inRange(item Item) bool {
-	return !item.Less(st.fromKey) && !st.toKey.Less(item)
+	return !item.Less(st.fromKey) && st.belowTo(item)
+}
+
+// Returns true if the given item does not exceed the upper bound of the subTree
+func (st *subTree) belowTo(item Item) bool {
+	if st.toKey == nil {
+		return true
+	}
+
+	if st.exclusive {
+		return item.Less(st.toKey)
+	}
+
+	return !st.toKey.Less(item)
+}
+
+// Returns the greatest node that does not exceed the upper bound of the subTree
+func (st *subTree) last() *node {
+	root := st.tree.root
+	if root == tNil {
+		return tNil
+	}
+
+	if st.toKey == nil {
+		return root.max()
+	}
+
+	x := root.floor(st.toKey)
+	if x != tNil && !st.belowTo(x.item) {
+		x = x.prev()
+	}
+
+	return x
 }
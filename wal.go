@@ -0,0 +1,328 @@
+package rbtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// maxRecordSize limits the payload of a single log record.
+const maxRecordSize = 64 << 20
+
+// opBatch is the kind of a record that holds all operations of a batch: the uvarint
+// count of operations followed by the kind, uvarint length and encoded item of each.
+const opBatch OpKind = 0xff
+
+// ErrorCorruptedLog tells that a write-ahead log record is damaged.
+var ErrorCorruptedLog error = errors.New("Write-ahead log is corrupted")
+
+// ErrorRecordTooLarge tells that an encoded item exceeds the size limit of a log record.
+var ErrorRecordTooLarge error = errors.New("Encoded item is too large for a write-ahead log record")
+
+// ErrorLogFailed tells that the write-ahead log refuses records after a failed write.
+var ErrorLogFailed error = errors.New("Write-ahead log has failed to write a record")
+
+// Codec converts items to bytes and back for the write-ahead log.
+type Codec interface {
+	// Encode returns the binary representation of the given item.
+	Encode(item Item) ([]byte, error)
+	// Decode returns the item from its binary representation.
+	Decode(data []byte) (Item, error)
+}
+
+// WAL is a Tree that appends every mutation to a write-ahead log before applying it.
+// A tree can be restored by loading the last checkpoint and replaying the log with Recover.
+// Durability depends on the writer, e.g. *os.File should be synced by the caller.
+// A failed write may leave a partial record behind, so afterwards the WAL refuses
+// all mutations with ErrorLogFailed and the tree should be restored with Recover.
+// Keys are validated before logging for the trees of this package, other Tree
+// implementations must not reject keys passed to Insert or Remove.
+type WAL struct {
+	Tree
+	log *walLog
+}
+
+// walLog is a writer of log records shared by a WAL and its sub tree views.
+// A batch log stages operations in buf instead of writing them.
+type walLog struct {
+	w     io.Writer
+	codec Codec
+	buf   []byte
+	err   error // the error of a failed write
+	batch bool
+	count int // number of staged operations
+}
+
+// ranged is implemented by the trees of this package to validate keys before a mutation.
+// Wrappers forward it to the wrapped tree.
+type ranged interface {
+	inRange(item Item) bool
+}
+
+// accepts returns true if the given tree accepts the given key.
+// Trees that do not implement ranged accept any key.
+func accepts(tree Tree, item Item) bool {
+	if r, ok := tree.(ranged); ok {
+		return r.inRange(item)
+	}
+
+	return true
+}
+
+// inRange returns true if the wrapped tree accepts the given key.
+func (w *WAL) inRange(item Item) bool {
+	return accepts(w.Tree, item)
+}
+
+// NewWAL returns a WAL that logs mutations of the given tree to w.
+func NewWAL(tree Tree, w io.Writer, codec Codec) *WAL {
+	return &WAL{tree, &walLog{w: w, codec: codec}}
+}
+
+// Insert logs and adds the given item to the tree, see Tree.Insert.
+// Returns an error if the record cannot be written, the tree is left untouched then.
+func (w *WAL) Insert(item Item) (bool, error) {
+	if !accepts(w.Tree, item) {
+		return false, ErrorOutOfSubTreeRange
+	}
+
+	if err := w.log.append(OpInsert, item); err != nil {
+		return false, err
+	}
+
+	return w.Tree.Insert(item)
+}
+
+// Remove logs and deletes an item equals to the given item from the tree, see Tree.Remove.
+// Returns an error if the record cannot be written, the tree is left untouched then.
+func (w *WAL) Remove(item Item) (bool, error) {
+	if !accepts(w.Tree, item) {
+		return false, ErrorOutOfSubTreeRange
+	}
+
+	if w.Tree.Find(item) == nil {
+		return false, nil
+	}
+
+	if err := w.log.append(OpRemove, item); err != nil {
+		return false, err
+	}
+
+	return w.Tree.Remove(item)
+}
+
+// Batch calls fn with a transaction over the WAL, see Tree.Batch.
+// Operations of the transaction are staged and appended as a single record on commit,
+// so Recover replays either all of them or none.
+// If fn or the write fails, the changes are reverted on the tree.
+func (w *WAL) Batch(fn func(tx Txn) error) error {
+	stage := &WAL{w.Tree, &walLog{codec: w.log.codec, batch: true}}
+
+	return runBatch(stage, w.Tree, fn, func() error {
+		if stage.log.count == 0 {
+			return nil
+		}
+
+		payload := binary.AppendUvarint(nil, uint64(stage.log.count))
+		return w.log.write(opBatch, append(payload, stage.log.buf...))
+	})
+}
+
+// SubTree returns a logged view of the portion of this tree whose keys range from
+// fromKey, inclusive, to toKey, exclusive. The view shares the log with this WAL.
+func (w *WAL) SubTree(fromKey, toKey Item) (Tree, error) {
+	sub, err := w.Tree.SubTree(fromKey, toKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WAL{sub, w.log}, nil
+}
+
+// Recover replays the write-ahead log from r onto the given tree.
+// A truncated last record, which is left by a crash during the append, is ignored.
+func Recover(tree Tree, r io.Reader, codec Codec) error {
+	br := bufio.NewReader(r)
+
+	for {
+		kind, payload, err := readRecord(br)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		ops, err := decodeRecord(kind, payload, codec)
+		if err != nil {
+			return err
+		}
+
+		for _, op := range ops {
+			if err := apply(tree, op); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// append logs the given operation, a batch log stages it until the batch is committed.
+func (l *walLog) append(kind OpKind, item Item) error {
+	payload, err := l.codec.Encode(item)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxRecordSize {
+		return ErrorRecordTooLarge
+	}
+
+	if !l.batch {
+		return l.write(kind, payload)
+	}
+
+	if len(l.buf)+len(payload)+1+2*binary.MaxVarintLen64 > maxRecordSize {
+		return ErrorRecordTooLarge
+	}
+
+	l.buf = append(l.buf, byte(kind))
+	l.buf = binary.AppendUvarint(l.buf, uint64(len(payload)))
+	l.buf = append(l.buf, payload...)
+	l.count++
+
+	return nil
+}
+
+// write appends a record with the given kind and payload to the log.
+// The record layout is: kind, uvarint payload length, payload, CRC-32 of kind and payload.
+// Once a write fails, all subsequent ones return ErrorLogFailed.
+func (l *walLog) write(kind OpKind, payload []byte) error {
+	if l.err != nil {
+		return ErrorLogFailed
+	}
+
+	l.buf = append(l.buf[:0], byte(kind))
+	l.buf = binary.AppendUvarint(l.buf, uint64(len(payload)))
+	l.buf = append(l.buf, payload...)
+	l.buf = binary.BigEndian.AppendUint32(l.buf, checksum(kind, payload))
+
+	if _, err := l.w.Write(l.buf); err != nil {
+		l.err = err
+		return err
+	}
+
+	return nil
+}
+
+// decodeRecord returns the operations stored in a record with the given kind and payload.
+func decodeRecord(kind OpKind, payload []byte, codec Codec) ([]Operation, error) {
+	if kind != opBatch {
+		item, err := codec.Decode(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Operation{{Kind: kind, Item: item}}, nil
+	}
+
+	count, n := binary.Uvarint(payload)
+	if n <= 0 || count > uint64(len(payload)) {
+		return nil, ErrorCorruptedLog
+	}
+
+	ops := make([]Operation, 0, count)
+	for payload = payload[n:]; count > 0; count-- {
+		if len(payload) == 0 {
+			return nil, ErrorCorruptedLog
+		}
+
+		kind := OpKind(payload[0])
+		size, n := binary.Uvarint(payload[1:])
+		if (kind != OpInsert && kind != OpRemove) || n <= 0 || size > uint64(len(payload)-1-n) {
+			return nil, ErrorCorruptedLog
+		}
+
+		payload = payload[1+n:]
+		item, err := codec.Decode(payload[:size])
+		if err != nil {
+			return nil, err
+		}
+
+		ops = append(ops, Operation{Kind: kind, Item: item})
+		payload = payload[size:]
+	}
+
+	if len(payload) != 0 {
+		return nil, ErrorCorruptedLog
+	}
+
+	return ops, nil
+}
+
+// readRecord reads a single record written by walLog.append.
+func readRecord(r *bufio.Reader) (OpKind, []byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	kind := OpKind(b)
+	if kind != OpInsert && kind != OpRemove && kind != opBatch {
+		return 0, nil, ErrorCorruptedLog
+	}
+
+	n, err := readLength(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	data := make([]byte, n+4)
+	if _, err := io.ReadFull(r, data); err == io.EOF {
+		return 0, nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, nil, err
+	}
+
+	payload := data[:n]
+	if binary.BigEndian.Uint32(data[n:]) != checksum(kind, payload) {
+		return 0, nil, ErrorCorruptedLog
+	}
+
+	return kind, payload, nil
+}
+
+// readLength reads the uvarint payload length of a record.
+// Returns io.ErrUnexpectedEOF if the length is cut short, and ErrorCorruptedLog if it is malformed.
+func readLength(r *bufio.Reader) (uint64, error) {
+	var n uint64
+
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		n |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			if n > maxRecordSize {
+				return 0, ErrorCorruptedLog
+			}
+
+			return n, nil
+		}
+	}
+
+	return 0, ErrorCorruptedLog
+}
+
+// checksum returns the CRC-32 of the record kind and payload.
+func checksum(kind OpKind, payload []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE([]byte{byte(kind)}), crc32.IEEETable, payload)
+}
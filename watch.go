@@ -0,0 +1,139 @@
+package rbtree
+
+import "sync"
+
+// watchBufferSize is the capacity of a watcher channel.
+const watchBufferSize = 64
+
+// ChangeEvent describes a single tree mutation delivered by Tree.Watch.
+type ChangeEvent = Operation
+
+// watcher is a subscription to changes of items accepted by the given predicate.
+type watcher struct {
+	accept func(item Item) bool
+	events chan ChangeEvent
+	closed sync.Once
+}
+
+// watchList is a set of subscriptions, guarded so that they can be cancelled
+// from consumer goroutines while the tree is being modified.
+type watchList struct {
+	mu       sync.Mutex
+	watchers []*watcher
+	held     []Operation // events of running batches
+	holding  int         // number of running batches
+}
+
+// watch subscribes to changes of items accepted by the given predicate.
+func (rb *rbTree) watch(accept func(item Item) bool) (<-chan ChangeEvent, func()) {
+	w := &watcher{
+		accept: accept,
+		events: make(chan ChangeEvent, watchBufferSize),
+	}
+
+	rb.watchers.mu.Lock()
+	rb.watchers.watchers = append(rb.watchers.watchers, w)
+	rb.watchers.mu.Unlock()
+
+	return w.events, func() { rb.unwatch(w) }
+}
+
+// notify delivers the given operation to the watchers that accept the changed item.
+// It never blocks: a watcher whose buffer is full is dropped and its channel is closed.
+// Events are held instead while a batch is running, see holdEvents.
+func (rb *rbTree) notify(op Operation) {
+	rb.watchers.mu.Lock()
+	defer rb.watchers.mu.Unlock()
+
+	if rb.watchers.holding > 0 {
+		rb.watchers.held = append(rb.watchers.held, op)
+		return
+	}
+
+	active := rb.watchers.watchers[:0]
+	for _, w := range rb.watchers.watchers {
+		if w.accept(op.Item) {
+			select {
+			case w.events <- op:
+			default:
+				w.close()
+				continue
+			}
+		}
+
+		active = append(active, w)
+	}
+
+	clear(rb.watchers.watchers[len(active):])
+	rb.watchers.watchers = active
+}
+
+// holdEvents makes the tree backing the given one hold its change events until the
+// returned function is called, which either delivers or discards the events held since.
+// Events of nested holds are delivered when the outermost one is released.
+func holdEvents(tree Txn) func(deliver bool) {
+	rb := backing(tree)
+	if rb == nil {
+		return func(bool) {}
+	}
+
+	rb.watchers.mu.Lock()
+	rb.watchers.holding++
+	mark := len(rb.watchers.held)
+	rb.watchers.mu.Unlock()
+
+	return func(deliver bool) {
+		rb.watchers.mu.Lock()
+		rb.watchers.holding--
+		if !deliver {
+			clear(rb.watchers.held[mark:])
+			rb.watchers.held = rb.watchers.held[:mark]
+		}
+
+		var events []Operation
+		if rb.watchers.holding == 0 {
+			events, rb.watchers.held = rb.watchers.held, nil
+		}
+		rb.watchers.mu.Unlock()
+
+		for _, op := range events {
+			rb.notify(op)
+		}
+	}
+}
+
+// backing returns the rbTree underlying the given tree, or nil if it is not a tree of this package.
+func backing(tree Txn) *rbTree {
+	switch t := tree.(type) {
+	case *rbTree:
+		return t
+	case *subTree:
+		return t.tree
+	case *Journal:
+		return backing(t.Tree)
+	case *WAL:
+		return backing(t.Tree)
+	}
+
+	return nil
+}
+
+// unwatch cancels the given subscription and closes its channel.
+func (rb *rbTree) unwatch(w *watcher) {
+	rb.watchers.mu.Lock()
+	defer rb.watchers.mu.Unlock()
+
+	for i, x := range rb.watchers.watchers {
+		if x == w {
+			rb.watchers.watchers = append(rb.watchers.watchers[:i], rb.watchers.watchers[i+1:]...)
+			break
+		}
+	}
+
+	w.close()
+}
+
+// close closes the channel of the watcher once.
+func (w *watcher) close() {
+	w.closed.Do(func() { close(w.events) })
+}